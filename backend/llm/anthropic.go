@@ -0,0 +1,454 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultAnthropicBaseURL   = "https://api.anthropic.com/v1"
+	defaultAnthropicMaxTokens = 4096
+	anthropicVersion          = "2023-06-01"
+)
+
+// anthropicMaxOutputTokens maps model name prefixes to their output token
+// limit. Longer prefixes are listed before shorter ones they extend.
+var anthropicMaxOutputTokens = []struct {
+	prefix string
+	limit  int
+}{
+	{"claude-opus-4", 32000},
+	{"claude-sonnet-4", 64000},
+	{"claude-3-7-sonnet", 64000},
+	{"claude-3-5-sonnet", 8192},
+	{"claude-3-5-haiku", 8192},
+	{"claude-3-opus", 4096},
+	{"claude-3-haiku", 4096},
+}
+
+type AnthropicClient struct {
+	httpClient *http.Client
+	apiKey     string
+	model      string
+	opts       options
+	life       *lifecycle
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	MaxTokens   int                `json:"max_tokens"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	Temperature *float64           `json:"temperature,omitempty"`
+	Thinking    *anthropicThinking `json:"thinking,omitempty"`
+	Stream      bool               `json:"stream,omitempty"`
+
+	// cacheSystem sends System with cache_control, see WithCachePrefix.
+	cacheSystem bool
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicResponse struct {
+	Content    []anthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      anthropicUsage          `json:"usage"`
+	Error      *anthropicError         `json:"error,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicContentBlock struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	Thinking string `json:"thinking,omitempty"`
+}
+
+type anthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// NewAnthropicClient returns a client for Anthropic's Messages API.
+// max_tokens is required by the API; it defaults to 4096 unless set with
+// WithMaxTokens.
+func NewAnthropicClient(apiKey, model string, opts ...Option) *AnthropicClient {
+	if apiKey == "" {
+		panic("API Key must be provided")
+	}
+
+	if model == "" {
+		model = DefaultModel("anthropic")
+	}
+
+	o := defaultOptions()
+	o.provider = "anthropic"
+	o.defaultMaxTokens = defaultAnthropicMaxTokens
+	o.baseURL = defaultAnthropicBaseURL
+	o.chatPath = "/messages"
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &AnthropicClient{
+		httpClient: o.newHTTPClient(),
+		apiKey:     apiKey,
+		model:      model,
+		opts:       o,
+		life:       newLifecycle(),
+	}
+}
+
+func (a *AnthropicClient) GenerateCode(ctx context.Context, prompt string) (string, error) {
+	result, err := a.Generate(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return result.Code, nil
+}
+
+func (a *AnthropicClient) Generate(ctx context.Context, prompt string) (*GenerationResult, error) {
+	ctx, end, err := a.life.begin(ctx)
+	if err != nil {
+		return nil, a.opts.providerError(err)
+	}
+
+	start := a.opts.clock.Now()
+	a.opts.logRequest(ctx, a.model, prompt)
+
+	callCtx, cancel := a.opts.callContext(ctx)
+	defer cancel()
+
+	result, err := a.opts.generate(ctx, func() (*GenerationResult, error) {
+		return a.send(callCtx, prompt)
+	})
+	err = a.opts.providerError(end(err))
+
+	var content string
+	if result != nil {
+		content = result.Code
+	}
+	a.opts.logResponse(ctx, a.model, content, a.opts.clock.Now().Sub(start), err)
+
+	return result, err
+}
+
+func (a *AnthropicClient) send(ctx context.Context, prompt string) (*GenerationResult, error) {
+	request, err := a.buildRequest(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := a.opts.newJSONRequest(ctx, a.opts.chatMethod, a.opts.endpointURL(a.opts.chatPath), request, a.authorize)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := doJSONRequest(a.httpClient, req, a.opts.retryPolicy())
+	if err != nil {
+		return nil, fmt.Errorf("failed to call API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+
+	if err := checkContentType(resp, "application/json"); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, bodySnippet(body))
+	}
+
+	var anthropicResp anthropicResponse
+	if err := json.Unmarshal(body, &anthropicResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if anthropicResp.Error != nil {
+		return nil, &APIError{Type: anthropicResp.Error.Type, Message: anthropicResp.Error.Message, Body: string(body)}
+	}
+
+	if anthropicResp.StopReason == "refusal" {
+		return nil, ErrContentFiltered
+	}
+
+	// Thinking blocks are kept apart from the text so they can't end up in
+	// the code.
+	var text, thinking strings.Builder
+	for _, block := range anthropicResp.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "thinking":
+			thinking.WriteString(block.Thinking)
+		}
+	}
+
+	if text.Len() == 0 {
+		return nil, errors.New("empty response")
+	}
+
+	result := &GenerationResult{
+		Code:  text.String(),
+		Raw:   text.String(),
+		Usage: Usage{PromptTokens: anthropicResp.Usage.InputTokens, CompletionTokens: anthropicResp.Usage.OutputTokens},
+		Model: request.Model,
+	}
+	if a.opts.returnThinking {
+		result.Reasoning = thinking.String()
+	}
+	if err := a.opts.checkOutput(ctx, result); err != nil {
+		return nil, err
+	}
+	if a.opts.captureSentMessages {
+		result.SentMessages = request.sentMessages()
+	}
+	return result, nil
+}
+
+// sentMessages returns r's messages with the top-level system prompt first.
+func (r anthropicRequest) sentMessages() []Message {
+	var out []Message
+	if r.System != "" {
+		out = append(out, Message{Role: RoleSystem, Content: r.System})
+	}
+	for _, m := range r.Messages {
+		out = append(out, Message{Role: Role(m.Role), Content: m.Content})
+	}
+	return out
+}
+
+// anthropicStreamEvent is one event of a Messages API stream. Its type
+// says which of the other fields are set.
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Message *struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	Delta *struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *anthropicUsage `json:"usage"`
+	Error *anthropicError `json:"error"`
+}
+
+// GenerateCodeStream streams the model output to w, writing the text of
+// each content_block_delta event as it arrives.
+func (a *AnthropicClient) GenerateCodeStream(ctx context.Context, prompt string, w io.Writer) error {
+	ctx, end, err := a.life.begin(ctx)
+	if err != nil {
+		return a.opts.providerError(err)
+	}
+
+	start := a.opts.clock.Now()
+	a.opts.logRequest(ctx, a.model, prompt)
+
+	callCtx, cancel := a.opts.callContext(ctx)
+	defer cancel()
+
+	err = a.opts.providerError(end(a.stream(callCtx, prompt, w)))
+	a.opts.logStreamEnd(ctx, a.model, a.opts.clock.Now().Sub(start), err)
+
+	return err
+}
+
+func (a *AnthropicClient) stream(ctx context.Context, prompt string, w io.Writer) error {
+	request, err := a.buildRequest(ctx, prompt)
+	if err != nil {
+		return err
+	}
+	request.Stream = true
+
+	var received strings.Builder
+	out := io.MultiWriter(w, &received)
+	messages := request.Messages
+
+	var total Usage
+	if a.opts.streamUsage != nil {
+		defer func() { a.opts.streamUsage(total) }()
+	}
+
+	for retries, reconnects := 0, 0; ; {
+		var usage Usage
+		err = a.streamOnce(ctx, request, out, &usage)
+		total.PromptTokens += usage.PromptTokens
+		total.CompletionTokens += usage.CompletionTokens
+
+		var readErr *sseReadError
+		if err == nil || ctx.Err() != nil || !errors.As(err, &readErr) {
+			return err
+		}
+		if received.Len() == 0 {
+			if !a.opts.retryStream(ctx, request.Model, &retries, err) {
+				return err
+			}
+			continue
+		}
+
+		request.Messages = append(messages[:len(messages):len(messages)],
+			anthropicMessage{Role: string(RoleAssistant), Content: received.String()},
+			anthropicMessage{Role: string(RoleUser), Content: streamContinuePrompt},
+		)
+		if reconnects >= a.opts.streamReconnects || a.opts.checkPromptSize(request.Model, request.sentMessages()) != nil {
+			return err
+		}
+		reconnects++
+
+		if a.opts.logger != nil {
+			a.opts.logger.WarnContext(ctx, "llm stream interrupted, reconnecting",
+				"model", request.Model, "attempt", reconnects, "received", received.Len(), "error", err)
+		}
+	}
+}
+
+// streamOnce runs one streaming request, writing text to w and recording
+// the reported usage in usage.
+func (a *AnthropicClient) streamOnce(ctx context.Context, request anthropicRequest, w io.Writer, usage *Usage) error {
+	req, err := a.opts.newJSONRequest(ctx, a.opts.chatMethod, a.opts.endpointURL(a.opts.chatPath), request, a.authorize)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := doJSONRequest(a.httpClient, req, a.opts.retryPolicy())
+	if err != nil {
+		return fmt.Errorf("failed to call API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return newAPIError(resp.StatusCode, body)
+	}
+
+	if err := checkContentType(resp, "text/event-stream"); err != nil {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, bodySnippetLen+1))
+		return fmt.Errorf("%w: %s", err, bodySnippet(body))
+	}
+
+	// The stream ends with message_stop rather than a [DONE] sentinel. The
+	// event: lines are redundant with each payload's type and are ignored.
+	err = readSSEUntilDone(resp.Body, func(data string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("failed to parse stream event: %w", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				usage.PromptTokens = event.Message.Usage.InputTokens
+				usage.CompletionTokens = event.Message.Usage.OutputTokens
+			}
+		case "content_block_delta":
+			if event.Delta == nil || event.Delta.Type != "text_delta" || event.Delta.Text == "" {
+				return nil
+			}
+			if _, err := io.WriteString(w, event.Delta.Text); err != nil {
+				return fmt.Errorf("failed to write stream output: %w", err)
+			}
+		case "message_delta":
+			// Output tokens are cumulative for the message.
+			if event.Usage != nil {
+				usage.CompletionTokens = event.Usage.OutputTokens
+			}
+			if event.Delta != nil && event.Delta.StopReason == "refusal" {
+				return ErrContentFiltered
+			}
+		case "message_stop":
+			return errStreamDone
+		case "error":
+			if event.Error != nil {
+				return &APIError{Type: event.Error.Type, Message: event.Error.Message, Body: data}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if errors.Is(err, ErrContentFiltered) {
+			return err
+		}
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	return nil
+}
+
+func (a *AnthropicClient) buildRequest(ctx context.Context, prompt string) (anthropicRequest, error) {
+	messages, err := a.opts.buildMessages(ctx, prompt)
+	if err != nil {
+		return anthropicRequest{}, err
+	}
+	model := a.opts.routeModel(a.model, prompt)
+	recordModel(ctx, model)
+	if err := a.opts.checkPromptSize(model, messages); err != nil {
+		return anthropicRequest{}, err
+	}
+
+	maxTokens := a.opts.outputTokens()
+	if limit, ok := anthropicOutputLimit(model); ok && maxTokens > limit {
+		return anthropicRequest{}, fmt.Errorf("max_tokens %d exceeds the %d token output limit of %s", maxTokens, limit, model)
+	}
+
+	request := anthropicRequest{
+		Model:       model,
+		MaxTokens:   maxTokens,
+		Temperature: a.opts.temperature,
+		cacheSystem: a.opts.cachePrefix,
+	}
+	if request.Thinking, err = a.opts.anthropicThinkingConfig(maxTokens); err != nil {
+		return anthropicRequest{}, err
+	}
+	if request.Thinking != nil {
+		request.Temperature = nil
+	}
+
+	// The Messages API takes the system prompt as a top-level field.
+	for _, m := range messages {
+		if m.Role == RoleSystem {
+			request.System = m.Content
+			continue
+		}
+		request.Messages = append(request.Messages, anthropicMessage{Role: string(m.Role), Content: m.Content})
+	}
+
+	return request, nil
+}
+
+func (a *AnthropicClient) authorize(req *http.Request) error {
+	req.Header.Set("x-api-key", a.apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+	return nil
+}
+
+func anthropicOutputLimit(model string) (int, bool) {
+	for _, m := range anthropicMaxOutputTokens {
+		if strings.HasPrefix(model, m.prefix) {
+			return m.limit, true
+		}
+	}
+	return 0, false
+}
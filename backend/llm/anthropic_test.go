@@ -0,0 +1,210 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/egedolmaci/scaffolder/backend/llm/testutil"
+)
+
+func writeAnthropicResponse(w http.ResponseWriter, text string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(anthropicResponse{
+		Content:    []anthropicContentBlock{{Type: "text", Text: text}},
+		StopReason: "end_turn",
+	})
+}
+
+func TestAnthropicClient_GenerateCode(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/messages" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "test-key" || r.Header.Get("anthropic-version") != anthropicVersion {
+			t.Errorf("unexpected headers: %v", r.Header)
+		}
+
+		var req anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			return
+		}
+		if req.System != systemPrompt {
+			t.Errorf("system prompt not sent as top-level field")
+		}
+		if len(req.Messages) != 1 || req.Messages[0].Role != "user" || req.Messages[0].Content != "hello" {
+			t.Errorf("unexpected messages: %+v", req.Messages)
+		}
+		writeAnthropicResponse(w, "<html></html>")
+	})
+
+	client := NewAnthropicClient("test-key", "", WithBaseURL(server.URL))
+
+	code, err := client.GenerateCode(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	if code != "<html></html>" {
+		t.Errorf("unexpected code %q", code)
+	}
+}
+
+func TestAnthropicClient_MaxTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want int
+	}{
+		{"default", nil, defaultAnthropicMaxTokens},
+		{"configured", []Option{WithMaxTokens(8000)}, 8000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				var req anthropicRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+					return
+				}
+				if req.MaxTokens != tt.want {
+					t.Errorf("max_tokens = %d, want %d", req.MaxTokens, tt.want)
+				}
+				writeAnthropicResponse(w, "ok")
+			})
+
+			opts := append([]Option{WithBaseURL(server.URL)}, tt.opts...)
+			client := NewAnthropicClient("test-key", "claude-sonnet-4-20250514", opts...)
+			if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+				t.Fatalf("GenerateCode failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestAnthropicClient_MaxTokensExceedsModelLimit(t *testing.T) {
+	client := NewAnthropicClient("test-key", "claude-3-haiku-20240307", WithMaxTokens(10000))
+
+	_, err := client.GenerateCode(context.Background(), "hello")
+	if err == nil || !strings.Contains(err.Error(), "exceeds the 4096 token output limit") {
+		t.Fatalf("expected output limit error, got %v", err)
+	}
+}
+
+// writeAnthropicStream writes a Messages API stream of text deltas, with
+// the event: lines the API sends before each payload.
+func writeAnthropicStream(w http.ResponseWriter, deltas ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	event := func(typ, data string) { fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typ, data) }
+
+	event("message_start", `{"type":"message_start","message":{"usage":{"input_tokens":12,"output_tokens":1}}}`)
+	event("content_block_start", `{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`)
+	event("ping", `{"type":"ping"}`)
+	for _, d := range deltas {
+		b, _ := json.Marshal(d)
+		event("content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":`+string(b)+`}}`)
+	}
+	event("content_block_stop", `{"type":"content_block_stop","index":0}`)
+	event("message_delta", `{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":7}}`)
+	event("message_stop", `{"type":"message_stop"}`)
+}
+
+func TestAnthropicClient_GenerateCodeStream(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req anthropicRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream {
+			t.Errorf("stream not requested")
+		}
+		writeAnthropicStream(w, "<html>", "<body></body>", "</html>")
+	})
+
+	var usage Usage
+	client := NewAnthropicClient("test-key", "", WithBaseURL(server.URL), WithStreamUsage(func(u Usage) { usage = u }))
+
+	var out strings.Builder
+	if err := client.GenerateCodeStream(context.Background(), "hello", &out); err != nil {
+		t.Fatalf("GenerateCodeStream failed: %v", err)
+	}
+	if out.String() != "<html><body></body></html>" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+	if usage != (Usage{PromptTokens: 12, CompletionTokens: 7}) {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+}
+
+func TestAnthropicClient_StreamErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []string
+		want   error
+	}{
+		{"refusal", []string{`{"type":"message_delta","delta":{"stop_reason":"refusal"}}`}, ErrContentFiltered},
+		{"truncated", []string{`{"type":"content_block_delta","delta":{"type":"text_delta","text":"<html>"}}`}, io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := testutil.NewSSEServer(t, testutil.SSEConfig{Chunks: tt.events, OmitDone: true})
+			client := NewAnthropicClient("test-key", "", WithBaseURL(server.URL))
+
+			err := client.GenerateCodeStream(context.Background(), "hello", io.Discard)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	t.Run("api error", func(t *testing.T) {
+		server := testutil.NewSSEServer(t, testutil.SSEConfig{
+			Chunks:   []string{`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`},
+			OmitDone: true,
+		})
+		client := NewAnthropicClient("test-key", "", WithBaseURL(server.URL))
+
+		err := client.GenerateCodeStream(context.Background(), "hello", io.Discard)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.Type != "overloaded_error" {
+			t.Fatalf("expected an overloaded APIError, got %v", err)
+		}
+	})
+}
+
+func TestAnthropicClient_StreamReconnect(t *testing.T) {
+	var calls int
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var req anthropicRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		if calls == 1 {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "data: %s\n\n", `{"type":"content_block_delta","delta":{"type":"text_delta","text":"<html>"}}`)
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+
+		n := len(req.Messages)
+		if n < 2 || req.Messages[n-2].Role != "assistant" || req.Messages[n-2].Content != "<html>" || req.Messages[n-1].Content != streamContinuePrompt {
+			t.Errorf("reconnect request missing partial output: %+v", req.Messages)
+		}
+		writeAnthropicStream(w, "</html>")
+	})
+
+	client := NewAnthropicClient("test-key", "", WithBaseURL(server.URL), WithStreamReconnect(1))
+
+	var out strings.Builder
+	if err := client.GenerateCodeStream(context.Background(), "hello", &out); err != nil {
+		t.Fatalf("GenerateCodeStream failed: %v", err)
+	}
+	if out.String() != "<html></html>" || calls != 2 {
+		t.Errorf("output %q after %d calls", out.String(), calls)
+	}
+}
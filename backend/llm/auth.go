@@ -0,0 +1,17 @@
+package llm
+
+import "context"
+
+// TokenProvider supplies bearer tokens that may change over time, such as
+// short-lived Azure AD tokens. Implementations should cache and refresh the
+// token themselves; Token is called once per request.
+type TokenProvider interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenProviderFunc adapts a function to the TokenProvider interface.
+type TokenProviderFunc func(ctx context.Context) (string, error)
+
+func (f TokenProviderFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
@@ -0,0 +1,41 @@
+package llm
+
+import (
+	"net/url"
+	"strings"
+)
+
+const defaultAzureAPIVersion = "2024-10-21"
+
+// NewAzureOpenAIClient returns a client for an Azure OpenAI deployment,
+// e.g. endpoint "https://my-resource.openai.azure.com". It authenticates
+// with the api-key header; pass WithTokenProvider for Entra ID (AAD) tokens,
+// in which case apiKey may be empty.
+func NewAzureOpenAIClient(endpoint, deployment, apiKey string, opts ...Option) *OpenAIClient {
+	if endpoint == "" || deployment == "" {
+		panic("Azure endpoint and deployment must be provided")
+	}
+
+	o := defaultOptions()
+	o.baseURL = strings.TrimRight(endpoint, "/") + "/openai/deployments/" + url.PathEscape(deployment)
+	o.apiVersion = defaultAzureAPIVersion
+	o.apiKeyHeader = true
+	o.provider = "azure"
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if apiKey == "" && o.tokenProvider == nil {
+		panic("API Key or token provider must be provided")
+	}
+
+	return newOpenAIClient(apiKey, deployment, o)
+}
+
+// WithAzureAPIVersion overrides the api-version query parameter sent to
+// Azure OpenAI.
+func WithAzureAPIVersion(version string) Option {
+	return func(o *options) {
+		o.apiVersion = version
+	}
+}
@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAzureOpenAIClient_APIKey(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/deployments/my-gpt4/chat/completions" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if v := r.URL.Query().Get("api-version"); v != defaultAzureAPIVersion {
+			t.Errorf("unexpected api-version %q", v)
+		}
+		if r.Header.Get("api-key") != "azure-key" || r.Header.Get("Authorization") != "" {
+			t.Errorf("unexpected auth headers: %v", r.Header)
+		}
+		writeChatResponse(w, "ok")
+	})
+
+	client := NewAzureOpenAIClient(server.URL, "my-gpt4", "azure-key")
+	if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+}
+
+func TestAzureOpenAIClient_TokenProvider(t *testing.T) {
+	var issued atomic.Int32
+	tokens := TokenProviderFunc(func(ctx context.Context) (string, error) {
+		if issued.Add(1) == 1 {
+			return "token-1", nil
+		}
+		return "token-2", nil
+	})
+
+	var seen []string
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("api-key") != "" {
+			t.Errorf("api-key header should not be sent with token auth")
+		}
+		seen = append(seen, r.Header.Get("Authorization"))
+		writeChatResponse(w, "ok")
+	})
+
+	client := NewAzureOpenAIClient(server.URL, "my-gpt4", "", WithTokenProvider(tokens))
+	for i := 0; i < 2; i++ {
+		if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+			t.Fatalf("GenerateCode failed: %v", err)
+		}
+	}
+
+	if len(seen) != 2 || seen[0] != "Bearer token-1" || seen[1] != "Bearer token-2" {
+		t.Errorf("token not resolved per request: %v", seen)
+	}
+}
@@ -0,0 +1,147 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// BudgetProvider fails calls with ErrBudgetExceeded once the cumulative
+// estimated cost of its responses exceeds a USD limit, or their total
+// tokens exceed a token limit. Reaching a limit exactly is allowed.
+//
+// Usage comes from the response when the wrapped provider is a
+// ResultProvider, otherwise it is estimated from the prompt and output
+// with EstimateTokens. A ResultProvider that reports zero usage, such as a
+// server omitting the usage object, costs nothing and never trips the
+// limit. Calls already in flight when the limit is crossed still complete,
+// so the total can overshoot by their cost.
+type BudgetProvider struct {
+	provider   Provider
+	model      string
+	limit      float64
+	tokenLimit int
+
+	mu     sync.Mutex
+	spent  float64
+	tokens int
+}
+
+// NewBudgetProvider caps p at limitUSD, pricing its usage at model's rates,
+// or those of GenerationResult.Model when a response reports a model with
+// known pricing, e.g. a fallback model. It returns ErrUnknownModelPrice when model has no known pricing.
+func NewBudgetProvider(p Provider, model string, limitUSD float64) (*BudgetProvider, error) {
+	if _, err := EstimateCost(model, Usage{}); err != nil {
+		return nil, err
+	}
+	return &BudgetProvider{provider: p, model: model, limit: limitUSD}, nil
+}
+
+// NewTokenBudgetProvider caps p at maxTokens prompt and completion tokens
+// in total, as reported by each response.
+func NewTokenBudgetProvider(p ResultProvider, maxTokens int) *BudgetProvider {
+	return &BudgetProvider{provider: resultProvider{p}, tokenLimit: maxTokens}
+}
+
+func (b *BudgetProvider) GenerateCode(ctx context.Context, prompt string) (string, error) {
+	result, err := b.Generate(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return result.Code, nil
+}
+
+func (b *BudgetProvider) Generate(ctx context.Context, prompt string) (*GenerationResult, error) {
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+
+	result, err := b.generate(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	var cost float64
+	if b.tokenLimit == 0 {
+		cost = b.cost(result)
+	}
+	b.mu.Lock()
+	b.spent += cost
+	b.tokens += result.Usage.PromptTokens + result.Usage.CompletionTokens
+	b.mu.Unlock()
+
+	return result, nil
+}
+
+// cost prices result at the rates of the model that produced it, falling
+// back to the budget's model when the result doesn't say or that model has
+// no known pricing.
+func (b *BudgetProvider) cost(result *GenerationResult) float64 {
+	if result.Model != "" {
+		if cost, err := EstimateCost(result.Model, result.Usage); err == nil {
+			return cost
+		}
+	}
+	cost, _ := EstimateCost(b.model, result.Usage)
+	return cost
+}
+
+func (b *BudgetProvider) check() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.tokenLimit > 0 {
+		if b.tokens > b.tokenLimit {
+			return fmt.Errorf("%w: used %d of %d tokens", ErrBudgetExceeded, b.tokens, b.tokenLimit)
+		}
+		return nil
+	}
+	if b.spent > b.limit {
+		return fmt.Errorf("%w: spent $%.4f of $%.4f", ErrBudgetExceeded, b.spent, b.limit)
+	}
+	return nil
+}
+
+// Spent returns the estimated USD spent so far. It is zero for a token
+// budget.
+func (b *BudgetProvider) Spent() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.spent
+}
+
+// Tokens returns the prompt and completion tokens used so far.
+func (b *BudgetProvider) Tokens() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens
+}
+
+func (b *BudgetProvider) generate(ctx context.Context, prompt string) (*GenerationResult, error) {
+	if rp, ok := b.provider.(ResultProvider); ok {
+		return rp.Generate(ctx, prompt)
+	}
+
+	code, err := b.provider.GenerateCode(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GenerationResult{Code: code}
+	result.Usage.PromptTokens, _ = EstimateTokens(b.model, prompt)
+	result.Usage.CompletionTokens, _ = EstimateTokens(b.model, code)
+	return result, nil
+}
+
+// resultProvider adapts a ResultProvider to Provider.
+type resultProvider struct {
+	ResultProvider
+}
+
+func (r resultProvider) GenerateCode(ctx context.Context, prompt string) (string, error) {
+	result, err := r.Generate(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return result.Code, nil
+}
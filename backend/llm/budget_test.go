@@ -0,0 +1,188 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestEstimateCost(t *testing.T) {
+	cost, err := EstimateCost("gpt-4o-mini-2024-07-18", Usage{PromptTokens: 1_000_000, CompletionTokens: 1_000_000})
+	if err != nil {
+		t.Fatalf("EstimateCost failed: %v", err)
+	}
+	if math.Abs(cost-0.75) > 1e-9 {
+		t.Errorf("cost = %v, want 0.75", cost)
+	}
+
+	if _, err := EstimateCost("my-local-model", Usage{}); !errors.Is(err, ErrUnknownModelPrice) {
+		t.Errorf("expected ErrUnknownModelPrice, got %v", err)
+	}
+}
+
+func TestEstimatePromptCost(t *testing.T) {
+	prompt := "Build a todo app"
+	n, _ := EstimateTokens("gpt-4o", prompt)
+
+	cost, err := EstimatePromptCost("gpt-4o", "", prompt, 1000)
+	if err != nil {
+		t.Fatalf("EstimatePromptCost failed: %v", err)
+	}
+	want := (float64(n+4)*2.50 + 1000*10) / 1e6
+	if math.Abs(cost-want) > 1e-12 {
+		t.Errorf("cost = %v, want %v", cost, want)
+	}
+
+	withSystem, _ := EstimatePromptCost("gpt-4o", systemPrompt, prompt, 1000)
+	if withSystem <= cost {
+		t.Errorf("system prompt should add cost: %v <= %v", withSystem, cost)
+	}
+
+	if _, err := EstimatePromptCost("my-local-model", "", prompt, 1000); !errors.Is(err, ErrUnknownModelPrice) {
+		t.Errorf("expected ErrUnknownModelPrice, got %v", err)
+	}
+}
+
+func TestBudgetProvider_StopsAtLimit(t *testing.T) {
+	calls := 0
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"<html></html>"}}],"usage":{"prompt_tokens":1000,"completion_tokens":1000}}`))
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	// Each call costs $0.09 on gpt-4.
+	budget, err := NewBudgetProvider(client, "gpt-4", 0.15)
+	if err != nil {
+		t.Fatalf("NewBudgetProvider failed: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := budget.GenerateCode(context.Background(), "hello"); err != nil {
+			t.Fatalf("call %d failed: %v", i, err)
+		}
+	}
+
+	_, err = budget.GenerateCode(context.Background(), "hello")
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 upstream calls, got %d", calls)
+	}
+	if math.Abs(budget.Spent()-0.18) > 1e-9 {
+		t.Errorf("Spent() = %v, want 0.18", budget.Spent())
+	}
+}
+
+func TestBudgetProvider_PricesFallbackModel(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req openAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model == "gpt-4" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"message": "The model does not exist", "code": "model_not_found"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"<html></html>"}}],"usage":{"prompt_tokens":1000000,"completion_tokens":1000000}}`))
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithFallbackModel("gpt-4o-mini"))
+
+	budget, err := NewBudgetProvider(client, "gpt-4", 100)
+	if err != nil {
+		t.Fatalf("NewBudgetProvider failed: %v", err)
+	}
+	if _, err := budget.GenerateCode(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+
+	// gpt-4o-mini's rates, not the $90 the same usage costs on gpt-4.
+	if math.Abs(budget.Spent()-0.75) > 1e-9 {
+		t.Errorf("Spent() = %v, want 0.75", budget.Spent())
+	}
+}
+
+type staticProvider string
+
+func (s staticProvider) GenerateCode(ctx context.Context, prompt string) (string, error) {
+	return string(s), nil
+}
+
+func TestBudgetProvider_EstimatesWithoutUsage(t *testing.T) {
+	budget, err := NewBudgetProvider(staticProvider("<html></html>"), "gpt-4", 1)
+	if err != nil {
+		t.Fatalf("NewBudgetProvider failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			budget.GenerateCode(context.Background(), "hello")
+		}()
+	}
+	wg.Wait()
+
+	if budget.Spent() <= 0 {
+		t.Errorf("expected estimated spend, got %v", budget.Spent())
+	}
+}
+
+func TestNewBudgetProvider_UnknownModel(t *testing.T) {
+	if _, err := NewBudgetProvider(staticProvider(""), "my-local-model", 1); !errors.Is(err, ErrUnknownModelPrice) {
+		t.Errorf("expected ErrUnknownModelPrice, got %v", err)
+	}
+}
+
+func TestTokenBudgetProvider(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"<html></html>"}}],"usage":{"prompt_tokens":300,"completion_tokens":200}}`))
+	})
+	client := NewOpenAIClient("test-key", "my-local-model", WithBaseURL(server.URL))
+	budget := NewTokenBudgetProvider(client, 1000)
+
+	for i := 0; i < 2; i++ {
+		if _, err := budget.GenerateCode(context.Background(), "hello"); err != nil {
+			t.Fatalf("call %d failed: %v", i, err)
+		}
+	}
+	if budget.Tokens() != 1000 {
+		t.Errorf("Tokens() = %d, want 1000", budget.Tokens())
+	}
+
+	// Using the budget exactly is allowed; going over it is not.
+	if _, err := budget.GenerateCode(context.Background(), "hello"); err != nil {
+		t.Fatalf("call at the limit failed: %v", err)
+	}
+	if _, err := budget.GenerateCode(context.Background(), "hello"); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+}
+
+func TestBudgetProvider_ZeroUsageNeverTrips(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeChatResponse(w, "<html></html>")
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+	budget, err := NewBudgetProvider(client, "gpt-4", 0)
+	if err != nil {
+		t.Fatalf("NewBudgetProvider failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := budget.GenerateCode(context.Background(), "hello"); err != nil {
+			t.Fatalf("call %d failed: %v", i, err)
+		}
+	}
+	if budget.Spent() != 0 {
+		t.Errorf("Spent() = %v, want 0", budget.Spent())
+	}
+}
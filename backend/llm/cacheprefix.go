@@ -0,0 +1,49 @@
+package llm
+
+import "encoding/json"
+
+// WithCachePrefix marks the system prompt as a cacheable prompt prefix, so
+// a large system prompt reused across calls is billed at the cached input
+// rate and processed faster after the first call.
+//
+// Only Anthropic needs the hint, which is sent as cache_control on the
+// system prompt; prompts below the model's minimum cacheable length (1024
+// tokens for most models) are not cached. OpenAI and Gemini 2.5 cache
+// repeated prefixes automatically, and the system prompt is always sent
+// first so it forms one, so for them this option has no effect.
+func WithCachePrefix(enabled bool) Option {
+	return func(o *options) {
+		o.cachePrefix = enabled
+	}
+}
+
+type anthropicCacheControl struct {
+	Type string `json:"type"`
+}
+
+type anthropicSystemBlock struct {
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text"`
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+// MarshalJSON sends the system prompt as a text block with cache_control
+// when it is to be cached, as the plain string form can't carry it.
+func (r anthropicRequest) MarshalJSON() ([]byte, error) {
+	type plain anthropicRequest
+	if !r.cacheSystem || r.System == "" {
+		return json.Marshal(plain(r))
+	}
+
+	return json.Marshal(struct {
+		plain
+		System []anthropicSystemBlock `json:"system"`
+	}{
+		plain: plain(r),
+		System: []anthropicSystemBlock{{
+			Type:         "text",
+			Text:         r.System,
+			CacheControl: &anthropicCacheControl{Type: "ephemeral"},
+		}},
+	})
+}
@@ -0,0 +1,47 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestAnthropicClient_WithCachePrefix(t *testing.T) {
+	var system json.RawMessage
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			System json.RawMessage `json:"system"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		system = req.System
+		writeAnthropicResponse(w, "ok")
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		client := NewAnthropicClient("test-key", "", WithBaseURL(server.URL), WithCachePrefix(true))
+		if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+			t.Fatalf("GenerateCode failed: %v", err)
+		}
+
+		var blocks []anthropicSystemBlock
+		if err := json.Unmarshal(system, &blocks); err != nil {
+			t.Fatalf("system prompt not sent as blocks: %s", system)
+		}
+		if len(blocks) != 1 || blocks[0].Text != systemPrompt || blocks[0].CacheControl == nil || blocks[0].CacheControl.Type != "ephemeral" {
+			t.Errorf("unexpected system blocks: %s", system)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		client := NewAnthropicClient("test-key", "", WithBaseURL(server.URL))
+		if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+			t.Fatalf("GenerateCode failed: %v", err)
+		}
+
+		var text string
+		if err := json.Unmarshal(system, &text); err != nil || text != systemPrompt {
+			t.Errorf("expected the plain system prompt, got %s", system)
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package llm
+
+import "time"
+
+// Clock is the time source for retry backoff and call durations. Tests can
+// substitute a fake to run backoff sequences instantly.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// WithClock replaces the real clock. Timeouts still run on real time since
+// they are enforced through the context.
+func WithClock(c Clock) Option {
+	return func(o *options) {
+		o.clock = c
+		o.retry.clock = c
+	}
+}
+
+func clockOrReal(c Clock) Clock {
+	if c == nil {
+		return realClock{}
+	}
+	return c
+}
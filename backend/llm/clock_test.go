@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock fires every timer immediately and records the requested
+// durations, advancing its own time by each.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestWithClock_BackoffSequence(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	})
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	client := NewOpenAIClient("test-key", "gpt-4",
+		WithBaseURL(server.URL),
+		WithClock(clock),
+		WithRetries(5),
+		WithBackoff(time.Second, 10*time.Second),
+		WithJitter(JitterNone),
+	)
+
+	start := time.Now()
+	if _, err := client.GenerateCode(context.Background(), "hello"); err == nil {
+		t.Fatal("expected error after retries")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("fake clock should make retries instant, took %v", elapsed)
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second}
+	if len(clock.sleeps) != len(want) {
+		t.Fatalf("sleeps = %v, want %v", clock.sleeps, want)
+	}
+	for i := range want {
+		if clock.sleeps[i] != want[i] {
+			t.Errorf("sleeps = %v, want %v", clock.sleeps, want)
+			break
+		}
+	}
+}
+
+func TestWithClock_DeadlineCheck(t *testing.T) {
+	var calls int
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	})
+
+	// By the fake clock the deadline is a minute away, too soon for the
+	// ten minute backoff to be worth waiting out.
+	clock := &fakeClock{now: time.Now().Add(59 * time.Minute)}
+	client := NewOpenAIClient("test-key", "gpt-4",
+		WithBaseURL(server.URL),
+		WithClock(clock),
+		WithRetries(1),
+		WithBackoff(10*time.Minute, 10*time.Minute),
+		WithJitter(JitterNone),
+		WithTimeout(0),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	if _, err := client.GenerateCode(ctx, "hello"); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 || len(clock.sleeps) != 0 {
+		t.Errorf("expected no retry past the deadline, got calls=%d sleeps=%v", calls, clock.sleeps)
+	}
+}
@@ -0,0 +1,37 @@
+package llm
+
+import (
+	"context"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// ConcurrencyLimitProvider caps the GenerateCode calls in flight to the
+// wrapped provider at max, for backends that limit simultaneous requests
+// rather than their rate. A call beyond the cap blocks until a slot frees
+// up or its context ends. Unlike WithMaxConcurrent it counts whole calls,
+// retries and tool rounds included, and works with any Provider. A max of
+// zero or less means no cap.
+type ConcurrencyLimitProvider struct {
+	provider Provider
+	sem      *semaphore.Weighted
+}
+
+func NewConcurrencyLimitProvider(p Provider, max int) *ConcurrencyLimitProvider {
+	c := &ConcurrencyLimitProvider{provider: p}
+	if max > 0 {
+		c.sem = semaphore.NewWeighted(int64(max))
+	}
+	return c
+}
+
+func (c *ConcurrencyLimitProvider) GenerateCode(ctx context.Context, prompt string) (string, error) {
+	if c.sem == nil {
+		return c.provider.GenerateCode(ctx, prompt)
+	}
+	if err := c.sem.Acquire(ctx, 1); err != nil {
+		return "", err
+	}
+	defer c.sem.Release(1)
+	return c.provider.GenerateCode(ctx, prompt)
+}
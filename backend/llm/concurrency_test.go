@@ -0,0 +1,75 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// peakProvider records the most calls it has had in flight at once.
+type peakProvider struct {
+	active, peak atomic.Int32
+}
+
+func (p *peakProvider) GenerateCode(ctx context.Context, prompt string) (string, error) {
+	n := p.active.Add(1)
+	defer p.active.Add(-1)
+	for {
+		peak := p.peak.Load()
+		if n <= peak || p.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	return prompt, nil
+}
+
+func TestConcurrencyLimitProvider_CapsInFlight(t *testing.T) {
+	upstream := &peakProvider{}
+	provider := NewConcurrencyLimitProvider(upstream, 3)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := provider.GenerateCode(context.Background(), "hello"); err != nil {
+				t.Errorf("GenerateCode failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak := upstream.peak.Load(); peak > 3 {
+		t.Errorf("expected at most 3 calls in flight, peak was %d", peak)
+	}
+}
+
+func TestConcurrencyLimitProvider_ContextCancelWhileQueued(t *testing.T) {
+	upstream := &blockingProvider{release: make(chan struct{})}
+	provider := NewConcurrencyLimitProvider(upstream, 1)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		provider.GenerateCode(context.Background(), "first")
+	}()
+	for upstream.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := provider.GenerateCode(ctx, "second"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if n := upstream.calls.Load(); n != 1 {
+		t.Errorf("queued call reached the provider: %d calls", n)
+	}
+
+	close(upstream.release)
+	<-done
+}
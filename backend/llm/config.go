@@ -0,0 +1,213 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Config gathers every client setting in one place so a provider can be
+// built from a config file or environment.
+//
+// ChatPath and ChatMethod tune the chat endpoint for OpenAI-compatible
+// servers that drift from the usual one, as WithChatPath and
+// WithChatMethod do. Gemini, whose path names the model, ignores ChatPath.
+type Config struct {
+	Provider    string
+	Model       string
+	APIKey      string
+	BaseURL     string
+	ChatPath    string
+	ChatMethod  string
+	Timeout     time.Duration
+	MaxTokens   int
+	Temperature *float64
+	Retries     int
+}
+
+// NewFromConfig validates cfg and returns the matching provider with all
+// settings applied.
+func NewFromConfig(cfg Config) (Provider, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(cfg.Provider) {
+	case "openai":
+		return NewOpenAIClient(cfg.APIKey, cfg.Model, cfg.options()...), nil
+	case "anthropic":
+		return NewAnthropicClient(cfg.APIKey, cfg.Model, cfg.options()...), nil
+	case "gemini":
+		return NewGeminiClient(cfg.APIKey, cfg.Model, cfg.options()...), nil
+	case "nim":
+		return NewNIMClient(cfg.APIKey, cfg.Model, cfg.options()...), nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q", cfg.Provider)
+	}
+}
+
+func (cfg Config) validate() error {
+	var errs []error
+
+	switch strings.ToLower(cfg.Provider) {
+	case "":
+		errs = append(errs, errors.New("config: provider is required"))
+	case "openai", "anthropic", "gemini", "nim":
+	default:
+		errs = append(errs, fmt.Errorf("config: unknown provider %q", cfg.Provider))
+	}
+	if cfg.APIKey == "" {
+		errs = append(errs, errors.New("config: api key is required"))
+	}
+	switch cfg.ChatMethod {
+	case "", http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		errs = append(errs, fmt.Errorf("config: chat method must be POST, PUT or PATCH, got %q", cfg.ChatMethod))
+	}
+	if cfg.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("config: timeout must not be negative, got %s", cfg.Timeout))
+	}
+	if cfg.MaxTokens < 0 {
+		errs = append(errs, fmt.Errorf("config: max tokens must not be negative, got %d", cfg.MaxTokens))
+	}
+	if maxTemp := maxTemperature(cfg.Provider); cfg.Temperature != nil && (*cfg.Temperature < 0 || *cfg.Temperature > maxTemp) {
+		errs = append(errs, fmt.Errorf("config: temperature must be between 0 and %g for %s, got %g", maxTemp, cfg.Provider, *cfg.Temperature))
+	}
+	if cfg.Retries < 0 {
+		errs = append(errs, fmt.Errorf("config: retries must not be negative, got %d", cfg.Retries))
+	}
+
+	return errors.Join(errs...)
+}
+
+// maxTemperature returns the highest temperature provider accepts. Anthropic
+// caps it at 1; OpenAI and Gemini allow up to 2.
+func maxTemperature(provider string) float64 {
+	if strings.EqualFold(provider, "anthropic") {
+		return 1
+	}
+	return 2
+}
+
+func (cfg Config) options() []Option {
+	var opts []Option
+
+	if cfg.BaseURL != "" {
+		opts = append(opts, WithBaseURL(cfg.BaseURL))
+	}
+	if cfg.ChatPath != "" {
+		opts = append(opts, WithChatPath(cfg.ChatPath))
+	}
+	if cfg.ChatMethod != "" {
+		opts = append(opts, WithChatMethod(cfg.ChatMethod))
+	}
+	if cfg.Timeout > 0 {
+		opts = append(opts, WithTimeout(cfg.Timeout))
+	}
+	if cfg.MaxTokens > 0 {
+		opts = append(opts, WithMaxTokens(cfg.MaxTokens))
+	}
+	if cfg.Temperature != nil {
+		opts = append(opts, WithTemperature(*cfg.Temperature))
+	}
+	if cfg.Retries > 0 {
+		opts = append(opts, WithRetries(cfg.Retries))
+	}
+
+	return opts
+}
+
+// fileConfig is the JSON layout read by LoadConfig.
+type fileConfig struct {
+	Provider    string   `json:"provider"`
+	Model       string   `json:"model"`
+	APIKey      string   `json:"api_key"`
+	BaseURL     string   `json:"base_url"`
+	ChatPath    string   `json:"chat_path"`
+	ChatMethod  string   `json:"chat_method"`
+	Timeout     string   `json:"timeout"`
+	MaxTokens   int      `json:"max_tokens"`
+	Temperature *float64 `json:"temperature"`
+	Retries     int      `json:"retries"`
+}
+
+// LoadConfig reads a JSON config file such as scaffolder.json:
+//
+//	{
+//	  "provider": "openai",
+//	  "api_key": "${OPENAI_API_KEY}",
+//	  "timeout": "90s"
+//	}
+//
+// ${VAR} references in api_key and base_url are expanded from the
+// environment so secrets can stay out of the file. Omitted fields keep
+// their defaults (the model falls back to DefaultModel) and the result is
+// validated as by NewFromConfig.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var fc fileConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&fc); err != nil {
+		return Config{}, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	cfg := Config{
+		Provider:    fc.Provider,
+		Model:       fc.Model,
+		ChatPath:    fc.ChatPath,
+		ChatMethod:  fc.ChatMethod,
+		MaxTokens:   fc.MaxTokens,
+		Temperature: fc.Temperature,
+		Retries:     fc.Retries,
+	}
+
+	var errs []error
+	if cfg.APIKey, err = expandEnv(fc.APIKey); err != nil {
+		errs = append(errs, fmt.Errorf("config: api key: %w", err))
+	}
+	if cfg.BaseURL, err = expandEnv(fc.BaseURL); err != nil {
+		errs = append(errs, fmt.Errorf("config: base url: %w", err))
+	}
+	if fc.Timeout != "" {
+		if cfg.Timeout, err = time.ParseDuration(fc.Timeout); err != nil {
+			errs = append(errs, fmt.Errorf("config: invalid timeout %q", fc.Timeout))
+		}
+	}
+	if cfg.Model == "" {
+		cfg.Model = DefaultModel(cfg.Provider)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return Config{}, err
+	}
+
+	if err := cfg.validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// expandEnv expands ${VAR} and $VAR in s, failing on unset variables.
+func expandEnv(s string) (string, error) {
+	var missing []string
+	out := os.Expand(s, func(name string) string {
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
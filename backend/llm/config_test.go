@@ -0,0 +1,165 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewFromConfig(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req openAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			return
+		}
+		if req.Model != "gpt-4o" || req.MaxTokens != 1000 || req.Temperature == nil || *req.Temperature != 0.2 {
+			t.Errorf("config not applied to request: %+v", req)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("unexpected auth header %q", r.Header.Get("Authorization"))
+		}
+		writeChatResponse(w, "ok")
+	})
+
+	temperature := 0.2
+	provider, err := NewFromConfig(Config{
+		Provider:    "openai",
+		Model:       "gpt-4o",
+		APIKey:      "test-key",
+		BaseURL:     server.URL,
+		Timeout:     10 * time.Second,
+		MaxTokens:   1000,
+		Temperature: &temperature,
+		Retries:     2,
+	})
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+
+	client := provider.(*OpenAIClient)
+	if client.opts.timeout != 10*time.Second || client.opts.retry.maxRetries != 2 {
+		t.Errorf("client options not applied: timeout=%v retries=%d", client.opts.timeout, client.opts.retry.maxRetries)
+	}
+
+	if _, err := provider.GenerateCode(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+}
+
+func TestNewFromConfig_ChatEndpoint(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/v1/completions" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		writeChatResponse(w, "ok")
+	})
+
+	provider, err := NewFromConfig(Config{
+		Provider:   "openai",
+		APIKey:     "test-key",
+		BaseURL:    server.URL,
+		ChatPath:   "/v1/completions",
+		ChatMethod: http.MethodPut,
+	})
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	if _, err := provider.GenerateCode(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+}
+
+func TestNewFromConfigValidation(t *testing.T) {
+	hot, warm := 3.0, 1.5
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{"missing provider", Config{APIKey: "k"}, "provider is required"},
+		{"missing api key", Config{Provider: "openai"}, "api key is required"},
+		{"unknown provider", Config{Provider: "acme", APIKey: "k"}, `unknown provider "acme"`},
+		{"negative retries", Config{Provider: "openai", APIKey: "k", Retries: -1}, "retries must not be negative"},
+		{"bodyless chat method", Config{Provider: "openai", APIKey: "k", ChatMethod: "GET"}, "chat method must be POST, PUT or PATCH"},
+		{"temperature out of range", Config{Provider: "openai", APIKey: "k", Temperature: &hot}, "temperature must be between 0 and 2"},
+		{"anthropic temperature out of range", Config{Provider: "anthropic", APIKey: "k", Temperature: &warm}, "temperature must be between 0 and 1 for anthropic"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFromConfig(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scaffolder.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("TEST_SCAFFOLDER_KEY", "sk-from-env")
+	path := writeConfigFile(t, `{
+		"provider": "openai",
+		"api_key": "${TEST_SCAFFOLDER_KEY}",
+		"timeout": "90s",
+		"temperature": 0.2,
+		"retries": 3,
+		"chat_path": "/v1/chat/completions"
+	}`)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.APIKey != "sk-from-env" {
+		t.Errorf("api key not expanded: %q", cfg.APIKey)
+	}
+	if cfg.Model != DefaultModel("openai") {
+		t.Errorf("model should default, got %q", cfg.Model)
+	}
+	if cfg.Timeout != 90*time.Second || cfg.Retries != 3 || cfg.Temperature == nil || *cfg.Temperature != 0.2 || cfg.ChatPath != "/v1/chat/completions" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"missing provider", `{"api_key": "sk"}`, "provider is required"},
+		{"unknown provider", `{"provider": "acme", "api_key": "sk"}`, `unknown provider "acme"`},
+		{"unset env var", `{"provider": "openai", "api_key": "${TEST_SCAFFOLDER_UNSET}"}`, "TEST_SCAFFOLDER_UNSET is not set"},
+		{"bad timeout", `{"provider": "openai", "api_key": "sk", "timeout": "soon"}`, "invalid timeout"},
+		{"unknown field", `{"provider": "openai", "api_key": "sk", "temprature": 1}`, "temprature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfigFile(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
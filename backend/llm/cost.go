@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Usage is the token usage of a single generation.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// modelPrices maps model name prefixes to USD per million input and output
+// tokens. Longer prefixes are listed before shorter ones they extend.
+var modelPrices = []struct {
+	prefix        string
+	input, output float64
+}{
+	{"gpt-4o-mini", 0.15, 0.60},
+	{"gpt-4o", 2.50, 10},
+	{"gpt-4.1-nano", 0.10, 0.40},
+	{"gpt-4.1-mini", 0.40, 1.60},
+	{"gpt-4.1", 2, 8},
+	{"gpt-4-turbo", 10, 30},
+	{"gpt-4", 30, 60},
+	{"gpt-3.5-turbo", 0.50, 1.50},
+	{"o1-mini", 1.10, 4.40},
+	{"o1", 15, 60},
+	{"o3-mini", 1.10, 4.40},
+	{"o3", 2, 8},
+	{"o4-mini", 1.10, 4.40},
+	{"claude-opus-4", 15, 75},
+	{"claude-sonnet-4", 3, 15},
+	{"claude-3-7-sonnet", 3, 15},
+	{"claude-3-5-sonnet", 3, 15},
+	{"claude-3-5-haiku", 0.80, 4},
+	{"claude-3-opus", 15, 75},
+	{"claude-3-haiku", 0.25, 1.25},
+}
+
+// EstimateCost returns the USD list price of u on model. Prices are a
+// snapshot and may lag the providers' published rates.
+func EstimateCost(model string, u Usage) (float64, error) {
+	for _, p := range modelPrices {
+		if strings.HasPrefix(model, p.prefix) {
+			return (float64(u.PromptTokens)*p.input + float64(u.CompletionTokens)*p.output) / 1e6, nil
+		}
+	}
+	return 0, fmt.Errorf("%w %q", ErrUnknownModelPrice, model)
+}
+
+// EstimatePromptCost returns an upper-bound USD estimate for sending
+// systemPrompt and prompt to model, assuming the full maxOutputTokens are
+// generated. An empty systemPrompt is left out. It returns
+// ErrUnknownModelPrice for models without pricing.
+func EstimatePromptCost(model, systemPrompt, prompt string, maxOutputTokens int) (float64, error) {
+	var messages []Message
+	if systemPrompt != "" {
+		messages = append(messages, Message{Role: RoleSystem, Content: systemPrompt})
+	}
+	messages = append(messages, Message{Role: RoleUser, Content: prompt})
+
+	promptTokens, err := countMessageTokens(model, messages)
+	if err != nil {
+		return 0, err
+	}
+	return EstimateCost(model, Usage{PromptTokens: promptTokens, CompletionTokens: maxOutputTokens})
+}
@@ -0,0 +1,27 @@
+package llm
+
+import (
+	"github.com/egedolmaci/scaffolder/backend/parser"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// DiffGenerations returns a line-based unified diff from generation a to
+// generation b, with three lines of context, or "" if they are identical.
+func DiffGenerations(a, b string) string {
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(a),
+		B:        difflib.SplitLines(b),
+		FromFile: "a",
+		ToFile:   "b",
+		Context:  3,
+	})
+	return diff
+}
+
+// DiffHTML is DiffGenerations for two generated pages, e.g. before and
+// after a refinement. Each is first extracted from its fenced block and
+// normalized as by parser.NormalizeHTML, so differences in fences and
+// model artifacts don't show up as changes.
+func DiffHTML(a, b string) string {
+	return DiffGenerations(parser.NormalizeHTML(extractedCode(a)), parser.NormalizeHTML(extractedCode(b)))
+}
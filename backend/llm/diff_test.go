@@ -0,0 +1,31 @@
+package llm
+
+import "testing"
+
+func TestDiffGenerations(t *testing.T) {
+	a := "<html>\n<body>\n<h1>Todo</h1>\n</body>\n</html>"
+	b := "<html>\n<body>\n<h1>Todos</h1>\n</body>\n</html>"
+
+	want := "--- a\n+++ b\n@@ -1,5 +1,5 @@\n <html>\n <body>\n-<h1>Todo</h1>\n+<h1>Todos</h1>\n </body>\n </html>\n"
+	if got := DiffGenerations(a, b); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got := DiffGenerations(a, a); got != "" {
+		t.Errorf("identical inputs should give no diff, got %q", got)
+	}
+}
+
+func TestDiffHTML(t *testing.T) {
+	a := "Here is your page:\n```html\n<html>\n<body>\n<h1>Todo</h1>\n</body>\n</html>\n```"
+	b := "<html>\n<body>\n<h1>Todos</h1>\n</body>\n</html>\n"
+
+	want := "--- a\n+++ b\n@@ -1,5 +1,5 @@\n <html>\n <body>\n-<h1>Todo</h1>\n+<h1>Todos</h1>\n </body>\n </html>\n"
+	if got := DiffHTML(a, b); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got := DiffHTML(a, "```html\n<html>\n<body>\n<h1>Todo</h1>\n</body>\n</html>\n\n```"); got != "" {
+		t.Errorf("pages differing only in fences should give no diff, got %q", got)
+	}
+}
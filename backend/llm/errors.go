@@ -0,0 +1,92 @@
+package llm
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrPromptTooLarge        = errors.New("prompt exceeds token limit")
+	ErrEmptyPrompt           = errors.New("prompt is empty")
+	ErrUnexpectedContentType = errors.New("unexpected content type")
+	ErrBudgetExceeded        = errors.New("budget exceeded")
+	ErrUnknownModelPrice     = errors.New("no pricing for model")
+	ErrModelRefused          = errors.New("model refused the request")
+	ErrContentFiltered       = errors.New("content blocked by provider filter")
+	ErrExternalResource      = errors.New("generated code loads external resources")
+	ErrContentFlagged        = errors.New("prompt flagged by moderation")
+	ErrHeadlessValidation    = errors.New("generated page failed headless validation")
+	ErrClosed                = errors.New("client closed")
+	ErrOutputTooShort        = errors.New("generated code is too short")
+	ErrInvalidResponse       = errors.New("generated code failed validation")
+)
+
+// APIError is an error reported by a provider's API, either as a non-200
+// response or inside a response or stream. StatusCode is zero in the
+// latter case. Message is the provider's error message, or the raw body if
+// it couldn't be parsed from it.
+//
+// Errors from the clients are prefixed with the provider's name, e.g.
+// "anthropic: API error (status 529): Overloaded", and wrap the APIError.
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Type       string
+	Code       string
+	Message    string
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if e.Code != "" {
+		msg += " (" + e.Code + ")"
+	}
+	if e.StatusCode == 0 {
+		return "API error: " + msg
+	}
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, msg)
+}
+
+// newAPIError builds the APIError for a non-200 response, taking the
+// message from the {"error": {...}} envelope OpenAI, Anthropic and Gemini
+// share.
+func newAPIError(status int, body []byte) *APIError {
+	e := &APIError{StatusCode: status, Body: string(body)}
+
+	var envelope struct {
+		Error *struct {
+			Type    string `json:"type"`
+			Status  string `json:"status"`
+			Code    any    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &envelope) == nil && envelope.Error != nil && envelope.Error.Message != "" {
+		e.Type = cmp.Or(envelope.Error.Type, envelope.Error.Status)
+		if code, ok := envelope.Error.Code.(string); ok {
+			e.Code = code
+		}
+		e.Message = envelope.Error.Message
+		return e
+	}
+
+	e.Message = strings.TrimSpace(string(body))
+	return e
+}
+
+// providerError prefixes err with the client's provider name and records
+// it in any APIError err wraps.
+func (o *options) providerError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		apiErr.Provider = o.provider
+	}
+	return fmt.Errorf("%s: %w", o.provider, err)
+}
@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAPIError_ProviderPrefix(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"type":"rate_limit_error","message":"slow down"}}`))
+	})
+
+	tests := []struct {
+		provider string
+		client   Provider
+	}{
+		{"openai", NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))},
+		{"anthropic", NewAnthropicClient("test-key", "claude-sonnet-4-20250514", WithBaseURL(server.URL))},
+		{"gemini", NewGeminiClient("test-key", "gemini-2.5-flash", WithBaseURL(server.URL))},
+		{"azure", NewAzureOpenAIClient(server.URL, "my-deployment", "test-key")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			_, err := tt.client.GenerateCode(context.Background(), "hello")
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected *APIError, got %v", err)
+			}
+			if apiErr.Provider != tt.provider || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Type != "rate_limit_error" || apiErr.Message != "slow down" {
+				t.Errorf("unexpected APIError: %+v", apiErr)
+			}
+			if want := tt.provider + ": API error (status 429): slow down"; err.Error() != want {
+				t.Errorf("error = %q, want %q", err, want)
+			}
+		})
+	}
+}
+
+func TestAPIError_InResponse(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"error":{"type":"server_error","code":"overloaded","message":"try again"}}`))
+	})
+
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+	_, err := client.GenerateCode(context.Background(), "hello")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 0 || apiErr.Code != "overloaded" {
+		t.Fatalf("expected in-band APIError, got %v", err)
+	}
+	if err.Error() != "openai: API error: try again (overloaded)" {
+		t.Errorf("error = %q", err)
+	}
+}
+
+func TestNewAPIError_UnparsedBody(t *testing.T) {
+	err := newAPIError(http.StatusBadGateway, []byte("<html>Bad Gateway</html>\n"))
+	if err.Message != "<html>Bad Gateway</html>" || !strings.Contains(err.Error(), "status 502") {
+		t.Errorf("unexpected error: %+v", err)
+	}
+}
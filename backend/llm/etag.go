@@ -0,0 +1,101 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// WithETagCache revalidates repeated requests against gateways that tag
+// responses with an ETag, such as Helicone or Portkey. The last response to
+// each distinct request body is kept, up to size entries, and its ETag sent
+// as If-None-Match; a 304 Not Modified answer returns the kept response with
+// zero usage since it wasn't billed again. Stock OpenAI sends no ETags, so
+// this is off by default. OpenAI clients only.
+func WithETagCache(size int) Option {
+	return func(o *options) {
+		if size <= 0 {
+			o.etags = nil
+			return
+		}
+		o.etags = &etagCache{size: size, entries: make(map[string]etagEntry)}
+	}
+}
+
+type etagEntry struct {
+	etag string
+	body []byte
+}
+
+// etagCache holds tagged responses keyed by a hash of the request URL and
+// body. When full, an arbitrary entry is evicted.
+type etagCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]etagEntry
+}
+
+// key identifies req by its URL and body, which must be replayable.
+func (c *etagCache) key(req *http.Request) string {
+	h := sha256.New()
+	io.WriteString(h, req.Method+" "+req.URL.String()+"\n")
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			io.Copy(h, body)
+			body.Close()
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// prepare sets If-None-Match on req when a tagged response is kept for it,
+// and returns req's cache key.
+func (c *etagCache) prepare(req *http.Request) string {
+	key := c.key(req)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		req.Header.Set("If-None-Match", e.etag)
+	}
+	return key
+}
+
+// lookup returns the response kept under key.
+func (c *etagCache) lookup(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	return e.body, ok
+}
+
+// store keeps body under key if resp carries an ETag. The usage is dropped
+// so that revalidated responses aren't counted twice.
+func (c *etagCache) store(key string, resp *http.Response, body []byte) {
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		return
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return
+	}
+	delete(fields, "usage")
+	stripped, err := json.Marshal(fields)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		for k := range c.entries {
+			delete(c.entries, k)
+			break
+		}
+	}
+	c.entries[key] = etagEntry{etag: etag, body: stripped}
+}
@@ -0,0 +1,61 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestOpenAIClient_ETagCache(t *testing.T) {
+	var calls int
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if calls > 1 {
+			t.Errorf("call %d: expected If-None-Match, got headers %v", calls, r.Header)
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"<html></html>"}}],"usage":{"prompt_tokens":10,"completion_tokens":5}}`))
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithETagCache(10))
+
+	first, err := client.Generate(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if first.Usage.PromptTokens != 10 {
+		t.Errorf("first call usage = %+v", first.Usage)
+	}
+
+	second, err := client.Generate(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("revalidated Generate failed: %v", err)
+	}
+	if second.Code != "<html></html>" || second.Usage != (Usage{}) {
+		t.Errorf("expected cached code without usage, got %+v", second)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 requests, got %d", calls)
+	}
+}
+
+func TestOpenAIClient_ETagCacheDisabled(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			t.Errorf("If-None-Match sent without WithETagCache")
+		}
+		w.Header().Set("ETag", `"v1"`)
+		writeChatResponse(w, "ok")
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+			t.Fatalf("GenerateCode failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package llm
+
+import (
+	"net/url"
+	"strings"
+)
+
+// FailoverStrategy is the order in which WithFailover tries endpoints.
+type FailoverStrategy int
+
+const (
+	// RetryThenFailover retries an endpoint up to its retry count, with
+	// backoff, before moving on to the next.
+	RetryThenFailover FailoverStrategy = iota
+	// ImmediateFailover moves to the next endpoint on every failure. Once
+	// all have been tried it backs off and makes another pass over those
+	// with retries left.
+	ImmediateFailover
+)
+
+// Endpoint is a failover target: an alternative base URL, such as another
+// region or gateway, serving the same API.
+type Endpoint struct {
+	BaseURL string
+	// Retries is how many times the endpoint is retried after its first
+	// attempt.
+	Retries int
+}
+
+// WithFailover sends requests that fail with a retryable error to the next
+// endpoint in turn, as ordered by strategy. The client's own base URL is
+// the first endpoint and is retried WithRetries times. Only the base URL
+// changes; each endpoint gets the same path, auth and body.
+//
+// In the worst case a request makes 1+Retries attempts on every endpoint,
+// the primary included, so with WithRetries(2) and two endpoints with
+// Retries 1 that is 3+2+2 = 7 attempts, plus the backoff between retries of
+// the same endpoint. Failing over to a new endpoint doesn't wait.
+func WithFailover(strategy FailoverStrategy, endpoints ...Endpoint) Option {
+	return func(o *options) {
+		o.retry.failoverStrategy = strategy
+		o.retry.endpoints = append([]Endpoint(nil), endpoints...)
+	}
+}
+
+// WithMaxRetriesPerEndpoint caps the retries of any one endpoint at n,
+// the primary included, overriding larger WithRetries and Endpoint.Retries
+// values. It bounds the worst case to (1+n) attempts per endpoint.
+func WithMaxRetriesPerEndpoint(n int) Option {
+	return func(o *options) {
+		o.retry.maxRetriesPerEndpoint = &n
+	}
+}
+
+// retryStep is one attempt of a request: the endpoint it goes to, with -1
+// meaning the primary base URL, and the backoff attempt to wait out first,
+// -1 for none.
+type retryStep struct {
+	endpoint int
+	backoff  int
+}
+
+// plan returns the attempts a request may make, in order.
+func (p retryPolicy) plan() []retryStep {
+	retries := []int{p.maxRetries}
+	for _, e := range p.endpoints {
+		retries = append(retries, e.Retries)
+	}
+	for i, n := range retries {
+		if p.maxRetriesPerEndpoint != nil && n > *p.maxRetriesPerEndpoint {
+			n = *p.maxRetriesPerEndpoint
+		}
+		retries[i] = max(n, 0)
+	}
+
+	var steps []retryStep
+	if p.failoverStrategy == ImmediateFailover {
+		for pass := 0; ; pass++ {
+			first := true
+			for i, n := range retries {
+				if n < pass {
+					continue
+				}
+				step := retryStep{endpoint: i - 1, backoff: -1}
+				if first && pass > 0 {
+					step.backoff = pass - 1
+				}
+				first = false
+				steps = append(steps, step)
+			}
+			if first {
+				return steps
+			}
+		}
+	}
+
+	for i, n := range retries {
+		for attempt := 0; attempt <= n; attempt++ {
+			steps = append(steps, retryStep{endpoint: i - 1, backoff: attempt - 1})
+		}
+	}
+	return steps
+}
+
+// endpointURL returns u with the primary base URL replaced by that of
+// failover endpoint i. u is returned unchanged if it isn't under the
+// primary base URL.
+func (p retryPolicy) endpointURL(u *url.URL, i int) (*url.URL, error) {
+	s := u.String()
+	if i < 0 || p.baseURL == "" || !strings.HasPrefix(s, p.baseURL) {
+		return u, nil
+	}
+	return url.Parse(strings.TrimRight(p.endpoints[i].BaseURL, "/") + s[len(p.baseURL):])
+}
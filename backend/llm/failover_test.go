@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Plan(t *testing.T) {
+	two := 2
+	tests := []struct {
+		name   string
+		policy retryPolicy
+		want   []retryStep
+	}{
+		{
+			name:   "no failover",
+			policy: retryPolicy{maxRetries: 2},
+			want:   []retryStep{{-1, -1}, {-1, 0}, {-1, 1}},
+		},
+		{
+			name:   "retry then failover",
+			policy: retryPolicy{maxRetries: 1, endpoints: []Endpoint{{Retries: 1}}},
+			want:   []retryStep{{-1, -1}, {-1, 0}, {0, -1}, {0, 0}},
+		},
+		{
+			name:   "immediate failover",
+			policy: retryPolicy{maxRetries: 2, endpoints: []Endpoint{{Retries: 1}}, failoverStrategy: ImmediateFailover},
+			want:   []retryStep{{-1, -1}, {0, -1}, {-1, 0}, {0, -1}, {-1, 1}},
+		},
+		{
+			name:   "capped",
+			policy: retryPolicy{maxRetries: 5, endpoints: []Endpoint{{Retries: 3}}, maxRetriesPerEndpoint: &two},
+			want:   []retryStep{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 0}, {0, 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.plan(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("plan = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAIClient_Failover(t *testing.T) {
+	for _, strategy := range []FailoverStrategy{RetryThenFailover, ImmediateFailover} {
+		var mu sync.Mutex
+		var hits []string
+		record := func(name string) {
+			mu.Lock()
+			defer mu.Unlock()
+			hits = append(hits, name)
+		}
+
+		primary := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			record("primary")
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+		})
+		secondary := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			record("secondary")
+			if r.URL.Path != "/chat/completions" {
+				t.Errorf("unexpected path %q", r.URL.Path)
+			}
+			writeChatResponse(w, "ok")
+		})
+
+		client := NewOpenAIClient("test-key", "gpt-4",
+			WithBaseURL(primary.URL),
+			WithRetries(1),
+			WithBackoff(time.Millisecond, time.Millisecond),
+			WithFailover(strategy, Endpoint{BaseURL: secondary.URL + "/"}),
+		)
+
+		result, err := client.GenerateCode(context.Background(), "hello")
+		if err != nil {
+			t.Fatalf("GenerateCode failed: %v", err)
+		}
+
+		want := "primary,primary,secondary"
+		if strategy == ImmediateFailover {
+			want = "primary,secondary"
+		}
+		if got := strings.Join(hits, ","); result != "ok" || got != want {
+			t.Errorf("strategy %d: result=%q hits=%s, want %s", strategy, result, got, want)
+		}
+	}
+}
+
+func TestOpenAIClient_FailoverExhausted(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}
+	primary := newTestServer(t, handler)
+	secondary := newTestServer(t, handler)
+
+	client := NewOpenAIClient("test-key", "gpt-4",
+		WithBaseURL(primary.URL),
+		WithRetries(4),
+		WithBackoff(time.Millisecond, time.Millisecond),
+		WithFailover(RetryThenFailover, Endpoint{BaseURL: secondary.URL, Retries: 4}),
+		WithMaxRetriesPerEndpoint(1),
+	)
+
+	if _, err := client.GenerateCode(context.Background(), "hello"); err == nil {
+		t.Fatal("expected an error once every endpoint failed")
+	}
+	if calls != 4 {
+		t.Errorf("expected 2 attempts per endpoint, got %d in total", calls)
+	}
+}
@@ -0,0 +1,35 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// contentFilteredError wraps ErrContentFiltered with the provider's
+// explanation, if it sent one.
+func contentFilteredError(detail json.RawMessage) error {
+	if len(detail) == 0 || string(detail) == "null" {
+		return ErrContentFiltered
+	}
+	return fmt.Errorf("%w: %s", ErrContentFiltered, detail)
+}
+
+// filteredPromptError recognises an error body rejecting the prompt itself,
+// as Azure OpenAI sends with code "content_filter".
+func filteredPromptError(body []byte) error {
+	var resp struct {
+		Error *struct {
+			Code       string          `json:"code"`
+			Message    string          `json:"message"`
+			InnerError json.RawMessage `json:"innererror"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &resp) != nil || resp.Error == nil || resp.Error.Code != "content_filter" {
+		return nil
+	}
+
+	if len(resp.Error.InnerError) > 0 {
+		return contentFilteredError(resp.Error.InnerError)
+	}
+	return fmt.Errorf("%w: %s", ErrContentFiltered, resp.Error.Message)
+}
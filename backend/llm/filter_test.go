@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIClient_ContentFiltered(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		detail string
+	}{
+		{
+			name:   "finish reason",
+			status: http.StatusOK,
+			body:   `{"choices":[{"finish_reason":"content_filter","message":{"role":"assistant","content":""},"content_filter_results":{"violence":{"filtered":true}}}]}`,
+			detail: "violence",
+		},
+		{
+			name:   "prompt feedback",
+			status: http.StatusOK,
+			body:   `{"choices":[],"prompt_feedback":{"block_reason":"SAFETY"}}`,
+			detail: "SAFETY",
+		},
+		{
+			name:   "azure prompt rejected",
+			status: http.StatusBadRequest,
+			body:   `{"error":{"code":"content_filter","message":"The response was filtered","innererror":{"code":"ResponsibleAIPolicyViolation","content_filter_result":{"hate":{"filtered":true}}}}}`,
+			detail: "ResponsibleAIPolicyViolation",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+			client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+			_, err := client.GenerateCode(context.Background(), "hello")
+			if !errors.Is(err, ErrContentFiltered) {
+				t.Fatalf("expected ErrContentFiltered, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.detail) {
+				t.Errorf("error %q missing detail %q", err, tt.detail)
+			}
+		})
+	}
+}
+
+func TestOpenAIClient_EmptyChoicesNotFiltered(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[]}`))
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	_, err := client.GenerateCode(context.Background(), "hello")
+	if err == nil || errors.Is(err, ErrContentFiltered) {
+		t.Fatalf("expected generic empty response error, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var ErrNoFixture = errors.New("no fixture matches prompt")
+
+// fixtureIndexFile optionally maps exact prompts to fixture file names.
+const fixtureIndexFile = "index.json"
+
+// FixtureProvider serves canned responses from a directory. A prompt is
+// matched first through dir/index.json ({"prompt": "file.html"}), then by a
+// file named FixtureName(prompt).
+type FixtureProvider struct {
+	dir string
+
+	// Default is the fixture file returned when nothing matches. When empty,
+	// unmatched prompts fail with ErrNoFixture.
+	Default string
+}
+
+func NewFixtureProvider(dir string) *FixtureProvider {
+	return &FixtureProvider{dir: dir}
+}
+
+// FixtureName returns the file name a prompt's fixture is stored under.
+func FixtureName(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:]) + ".html"
+}
+
+func (f *FixtureProvider) GenerateCode(ctx context.Context, prompt string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	name, err := f.lookup(prompt)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(filepath.Join(f.dir, name))
+	if err != nil {
+		return "", fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	return string(data), nil
+}
+
+func (f *FixtureProvider) lookup(prompt string) (string, error) {
+	index, err := os.ReadFile(filepath.Join(f.dir, fixtureIndexFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to read fixture index: %w", err)
+	}
+	if err == nil {
+		var names map[string]string
+		if err := json.Unmarshal(index, &names); err != nil {
+			return "", fmt.Errorf("failed to parse fixture index: %w", err)
+		}
+		if name, ok := names[prompt]; ok {
+			return name, nil
+		}
+	}
+
+	name := FixtureName(prompt)
+	if _, err := os.Stat(filepath.Join(f.dir, name)); err == nil {
+		return name, nil
+	}
+
+	if f.Default != "" {
+		return f.Default, nil
+	}
+
+	return "", ErrNoFixture
+}
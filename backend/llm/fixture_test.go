@@ -0,0 +1,54 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFixture(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFixtureProvider(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "index.json", `{"make a calculator": "calculator.html"}`)
+	writeFixture(t, dir, "calculator.html", "<calculator>")
+	writeFixture(t, dir, FixtureName("make a clock"), "<clock>")
+	writeFixture(t, dir, "default.html", "<default>")
+
+	provider := NewFixtureProvider(dir)
+	ctx := context.Background()
+
+	tests := []struct {
+		prompt string
+		want   string
+	}{
+		{"make a calculator", "<calculator>"},
+		{"make a clock", "<clock>"},
+	}
+	for _, tt := range tests {
+		got, err := provider.GenerateCode(ctx, tt.prompt)
+		if err != nil {
+			t.Fatalf("GenerateCode(%q) failed: %v", tt.prompt, err)
+		}
+		if got != tt.want {
+			t.Errorf("GenerateCode(%q) = %q, want %q", tt.prompt, got, tt.want)
+		}
+	}
+
+	if _, err := provider.GenerateCode(ctx, "unknown"); !errors.Is(err, ErrNoFixture) {
+		t.Errorf("expected ErrNoFixture, got %v", err)
+	}
+
+	provider.Default = "default.html"
+	got, err := provider.GenerateCode(ctx, "unknown")
+	if err != nil || got != "<default>" {
+		t.Errorf("default fixture: got %q, %v", got, err)
+	}
+}
@@ -0,0 +1,412 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const defaultGeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+type GeminiClient struct {
+	httpClient *http.Client
+	apiKey     string
+	model      string
+	opts       options
+	life       *lifecycle
+}
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent        `json:"contents"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig,omitzero"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiGenerationConfig struct {
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+}
+
+type geminiResponse struct {
+	Candidates     []geminiCandidate     `json:"candidates"`
+	PromptFeedback *geminiPromptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  *geminiUsage          `json:"usageMetadata,omitempty"`
+	Error          *geminiError          `json:"error,omitempty"`
+}
+
+type geminiCandidate struct {
+	Content      geminiContent `json:"content"`
+	FinishReason string        `json:"finishReason"`
+}
+
+type geminiPromptFeedback struct {
+	BlockReason string `json:"blockReason"`
+}
+
+type geminiUsage struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+}
+
+type geminiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+// NewGeminiClient returns a client for Google's Gemini API. The model is
+// part of the endpoint path, so WithChatPath has no effect.
+func NewGeminiClient(apiKey, model string, opts ...Option) *GeminiClient {
+	if apiKey == "" {
+		panic("API Key must be provided")
+	}
+
+	if model == "" {
+		model = DefaultModel("gemini")
+	}
+
+	o := defaultOptions()
+	o.provider = "gemini"
+	o.baseURL = defaultGeminiBaseURL
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &GeminiClient{
+		httpClient: o.newHTTPClient(),
+		apiKey:     apiKey,
+		model:      model,
+		opts:       o,
+		life:       newLifecycle(),
+	}
+}
+
+func (g *GeminiClient) GenerateCode(ctx context.Context, prompt string) (string, error) {
+	result, err := g.Generate(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return result.Code, nil
+}
+
+func (g *GeminiClient) Generate(ctx context.Context, prompt string) (*GenerationResult, error) {
+	ctx, end, err := g.life.begin(ctx)
+	if err != nil {
+		return nil, g.opts.providerError(err)
+	}
+
+	start := g.opts.clock.Now()
+	g.opts.logRequest(ctx, g.model, prompt)
+
+	callCtx, cancel := g.opts.callContext(ctx)
+	defer cancel()
+
+	result, err := g.opts.generate(ctx, func() (*GenerationResult, error) {
+		return g.send(callCtx, prompt)
+	})
+	err = g.opts.providerError(end(err))
+
+	var content string
+	if result != nil {
+		content = result.Code
+	}
+	g.opts.logResponse(ctx, g.model, content, g.opts.clock.Now().Sub(start), err)
+
+	return result, err
+}
+
+func (g *GeminiClient) send(ctx context.Context, prompt string) (*GenerationResult, error) {
+	model, request, err := g.buildRequest(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := g.opts.newJSONRequest(ctx, g.opts.chatMethod, g.endpoint(model, "generateContent"), request, g.authorize)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := doJSONRequest(g.httpClient, req, g.opts.retryPolicy())
+	if err != nil {
+		return nil, fmt.Errorf("failed to call API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+
+	if err := checkContentType(resp, "application/json"); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, bodySnippet(body))
+	}
+
+	var geminiResp geminiResponse
+	if err := json.Unmarshal(body, &geminiResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	text, err := geminiResp.text()
+	if err != nil {
+		return nil, err
+	}
+	if text == "" {
+		return nil, errors.New("empty response")
+	}
+
+	result := &GenerationResult{Code: text, Raw: text, Model: model}
+	if u := geminiResp.UsageMetadata; u != nil {
+		result.Usage = Usage{PromptTokens: u.PromptTokenCount, CompletionTokens: u.CandidatesTokenCount}
+	}
+	if err := g.opts.checkOutput(ctx, result); err != nil {
+		return nil, err
+	}
+	if g.opts.captureSentMessages {
+		result.SentMessages = request.sentMessages()
+	}
+	return result, nil
+}
+
+// text returns the text of the first candidate. It is used for whole
+// responses and stream chunks alike, which share this shape.
+func (r geminiResponse) text() (string, error) {
+	if r.Error != nil {
+		return "", &APIError{Type: r.Error.Status, Message: r.Error.Message}
+	}
+	if r.PromptFeedback != nil && r.PromptFeedback.BlockReason != "" {
+		return "", fmt.Errorf("%w: prompt blocked (%s)", ErrContentFiltered, r.PromptFeedback.BlockReason)
+	}
+	if len(r.Candidates) == 0 {
+		return "", nil
+	}
+
+	c := r.Candidates[0]
+	switch c.FinishReason {
+	case "SAFETY", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII":
+		return "", fmt.Errorf("%w: %s", ErrContentFiltered, c.FinishReason)
+	}
+
+	var text strings.Builder
+	for _, p := range c.Content.Parts {
+		text.WriteString(p.Text)
+	}
+	return text.String(), nil
+}
+
+// sentMessages returns r's contents with the system instruction first.
+func (r geminiRequest) sentMessages() []Message {
+	var out []Message
+	if r.SystemInstruction != nil {
+		out = append(out, Message{Role: RoleSystem, Content: r.SystemInstruction.Parts[0].Text})
+	}
+	for _, c := range r.Contents {
+		role := Role(c.Role)
+		if c.Role == "model" {
+			role = RoleAssistant
+		}
+		out = append(out, Message{Role: role, Content: c.Parts[0].Text})
+	}
+	return out
+}
+
+// GenerateCodeStream streams the model output to w from the
+// streamGenerateContent endpoint.
+func (g *GeminiClient) GenerateCodeStream(ctx context.Context, prompt string, w io.Writer) error {
+	ctx, end, err := g.life.begin(ctx)
+	if err != nil {
+		return g.opts.providerError(err)
+	}
+
+	start := g.opts.clock.Now()
+	g.opts.logRequest(ctx, g.model, prompt)
+
+	callCtx, cancel := g.opts.callContext(ctx)
+	defer cancel()
+
+	err = g.opts.providerError(end(g.stream(callCtx, prompt, w)))
+	g.opts.logStreamEnd(ctx, g.model, g.opts.clock.Now().Sub(start), err)
+
+	return err
+}
+
+func (g *GeminiClient) stream(ctx context.Context, prompt string, w io.Writer) error {
+	model, request, err := g.buildRequest(ctx, prompt)
+	if err != nil {
+		return err
+	}
+
+	var received strings.Builder
+	out := io.MultiWriter(w, &received)
+	contents := request.Contents
+
+	// Gemini reports usage on every chunk; each reconnect is billed as its
+	// own request.
+	var total Usage
+	if g.opts.streamUsage != nil {
+		defer func() { g.opts.streamUsage(total) }()
+	}
+
+	for retries, reconnects := 0, 0; ; {
+		var usage Usage
+		err = g.streamOnce(ctx, model, request, out, &usage)
+		total.PromptTokens += usage.PromptTokens
+		total.CompletionTokens += usage.CompletionTokens
+
+		var readErr *sseReadError
+		if err == nil || ctx.Err() != nil || !errors.As(err, &readErr) {
+			return err
+		}
+		if received.Len() == 0 {
+			if !g.opts.retryStream(ctx, model, &retries, err) {
+				return err
+			}
+			continue
+		}
+
+		request.Contents = append(contents[:len(contents):len(contents)],
+			geminiContent{Role: "model", Parts: []geminiPart{{Text: received.String()}}},
+			geminiContent{Role: "user", Parts: []geminiPart{{Text: streamContinuePrompt}}},
+		)
+		if reconnects >= g.opts.streamReconnects || g.opts.checkPromptSize(model, request.sentMessages()) != nil {
+			return err
+		}
+		reconnects++
+
+		if g.opts.logger != nil {
+			g.opts.logger.WarnContext(ctx, "llm stream interrupted, reconnecting",
+				"model", model, "attempt", reconnects, "received", received.Len(), "error", err)
+		}
+	}
+}
+
+// streamOnce runs one streaming request, writing text to w and recording
+// the reported usage in usage.
+func (g *GeminiClient) streamOnce(ctx context.Context, model string, request geminiRequest, w io.Writer, usage *Usage) error {
+	req, err := g.opts.newJSONRequest(ctx, g.opts.chatMethod, g.endpoint(model, "streamGenerateContent?alt=sse"), request, g.authorize)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := doJSONRequest(g.httpClient, req, g.opts.retryPolicy())
+	if err != nil {
+		return fmt.Errorf("failed to call API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return newAPIError(resp.StatusCode, body)
+	}
+
+	if err := checkContentType(resp, "text/event-stream"); err != nil {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, bodySnippetLen+1))
+		return fmt.Errorf("%w: %s", err, bodySnippet(body))
+	}
+
+	// Each event is a complete response holding only the new text. There is
+	// no [DONE] sentinel, so a finishReason marks the end instead, and a
+	// stream ending without one was cut short.
+	err = readSSEUntilDone(resp.Body, func(data string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+
+		// Usage is cumulative for the request, so the last chunk wins.
+		if u := chunk.UsageMetadata; u != nil {
+			*usage = Usage{PromptTokens: u.PromptTokenCount, CompletionTokens: u.CandidatesTokenCount}
+		}
+
+		text, err := chunk.text()
+		if err != nil {
+			return err
+		}
+		if text != "" {
+			if _, err := io.WriteString(w, text); err != nil {
+				return fmt.Errorf("failed to write stream output: %w", err)
+			}
+		}
+		if len(chunk.Candidates) > 0 && chunk.Candidates[0].FinishReason != "" {
+			return errStreamDone
+		}
+		return nil
+	})
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if errors.Is(err, ErrContentFiltered) {
+			return err
+		}
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	return nil
+}
+
+// buildRequest returns the routed model and the request for prompt.
+func (g *GeminiClient) buildRequest(ctx context.Context, prompt string) (string, geminiRequest, error) {
+	messages, err := g.opts.buildMessages(ctx, prompt)
+	if err != nil {
+		return "", geminiRequest{}, err
+	}
+	model := g.opts.routeModel(g.model, prompt)
+	recordModel(ctx, model)
+	if err := g.opts.checkPromptSize(model, messages); err != nil {
+		return "", geminiRequest{}, err
+	}
+
+	request := geminiRequest{
+		GenerationConfig: geminiGenerationConfig{
+			MaxOutputTokens: g.opts.maxTokens,
+			Temperature:     g.opts.temperature,
+		},
+	}
+
+	// Gemini takes the system prompt separately and calls the assistant
+	// role "model".
+	for _, m := range messages {
+		content := geminiContent{Role: string(m.Role), Parts: []geminiPart{{Text: m.Content}}}
+		switch m.Role {
+		case RoleSystem:
+			content.Role = ""
+			request.SystemInstruction = &content
+			continue
+		case RoleAssistant:
+			content.Role = "model"
+		}
+		request.Contents = append(request.Contents, content)
+	}
+
+	return model, request, nil
+}
+
+func (g *GeminiClient) endpoint(model, method string) string {
+	return g.opts.endpointURL("/models/" + model + ":" + method)
+}
+
+func (g *GeminiClient) authorize(req *http.Request) error {
+	req.Header.Set("x-goog-api-key", g.apiKey)
+	return nil
+}
@@ -0,0 +1,237 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/egedolmaci/scaffolder/backend/llm/testutil"
+)
+
+func writeGeminiResponse(w http.ResponseWriter, text string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(geminiResponse{
+		Candidates:    []geminiCandidate{{Content: geminiContent{Role: "model", Parts: []geminiPart{{Text: text}}}, FinishReason: "STOP"}},
+		UsageMetadata: &geminiUsage{PromptTokenCount: 7, CandidatesTokenCount: 3},
+	})
+}
+
+func geminiChunk(text string) string {
+	return fmt.Sprintf(`{"candidates":[{"content":{"role":"model","parts":[{"text":%q}]}}]}`, text)
+}
+
+func TestGeminiClient_Generate(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-2.5-flash:generateContent" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if r.Header.Get("x-goog-api-key") != "test-key" {
+			t.Errorf("unexpected headers: %v", r.Header)
+		}
+
+		var req geminiRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			return
+		}
+		if req.SystemInstruction == nil || req.SystemInstruction.Parts[0].Text != systemPrompt {
+			t.Errorf("system instruction not sent: %+v", req.SystemInstruction)
+		}
+		if len(req.Contents) != 1 || req.Contents[0].Role != "user" || req.Contents[0].Parts[0].Text != "hello" {
+			t.Errorf("unexpected contents: %+v", req.Contents)
+		}
+		if req.GenerationConfig.MaxOutputTokens != 100 {
+			t.Errorf("maxOutputTokens = %d", req.GenerationConfig.MaxOutputTokens)
+		}
+		writeGeminiResponse(w, "<html></html>")
+	})
+
+	client := NewGeminiClient("test-key", "", WithBaseURL(server.URL), WithMaxTokens(100))
+	result, err := client.Generate(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if result.Code != "<html></html>" || result.Usage != (Usage{PromptTokens: 7, CompletionTokens: 3}) {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestGeminiClient_PromptBlocked(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"promptFeedback":{"blockReason":"SAFETY"}}`)
+	})
+
+	client := NewGeminiClient("test-key", "gemini-2.5-flash", WithBaseURL(server.URL))
+	if _, err := client.GenerateCode(context.Background(), "hello"); !errors.Is(err, ErrContentFiltered) {
+		t.Fatalf("expected ErrContentFiltered, got %v", err)
+	}
+}
+
+func TestGeminiClient_GenerateCodeStream(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-2.5-flash:streamGenerateContent" || r.URL.Query().Get("alt") != "sse" {
+			t.Errorf("unexpected URL %q", r.URL)
+		}
+		// Gemini separates events with CRLF and sends usage on the last
+		// chunk, with no [DONE] sentinel.
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, text := range []string{"<html>", "<body>hi</body>", "</html>"} {
+			fmt.Fprintf(w, "data: %s\r\n\r\n", geminiChunk(text))
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"\"}]},\"finishReason\":\"STOP\"}],\"usageMetadata\":{\"promptTokenCount\":5}}\r\n\r\n")
+	})
+
+	client := NewGeminiClient("test-key", "gemini-2.5-flash", WithBaseURL(server.URL))
+
+	var out strings.Builder
+	if err := client.GenerateCodeStream(context.Background(), "hello", &out); err != nil {
+		t.Fatalf("GenerateCodeStream failed: %v", err)
+	}
+	if out.String() != "<html><body>hi</body></html>" {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
+
+func TestGeminiClient_StreamSafetyStop(t *testing.T) {
+	server := testutil.NewSSEServer(t, testutil.SSEConfig{
+		Chunks:   []string{geminiChunk("<html>"), `{"candidates":[{"finishReason":"SAFETY"}]}`},
+		OmitDone: true,
+	})
+	client := NewGeminiClient("test-key", "gemini-2.5-flash", WithBaseURL(server.URL))
+
+	var out strings.Builder
+	if err := client.GenerateCodeStream(context.Background(), "hello", &out); !errors.Is(err, ErrContentFiltered) {
+		t.Fatalf("expected ErrContentFiltered, got %v", err)
+	}
+	if out.String() != "<html>" {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
+
+func TestGeminiClient_StreamCancel(t *testing.T) {
+	server := testutil.NewSSEServer(t, testutil.SSEConfig{
+		Chunks: []string{geminiChunk("<p>tick</p>")},
+		Delay:  5 * time.Millisecond,
+		Loop:   true,
+	})
+	client := NewGeminiClient("test-key", "gemini-2.5-flash", WithBaseURL(server.URL))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := &cancelAfterWriter{n: 3, cancel: cancel}
+
+	if err := client.GenerateCodeStream(ctx, "hello", w); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if w.afterCancel != 0 {
+		t.Errorf("writer received %d writes after cancel", w.afterCancel)
+	}
+	select {
+	case <-server.Disconnected():
+	case <-time.After(time.Second):
+		t.Error("server did not see the client disconnect")
+	}
+}
+
+func TestGeminiClient_StreamUsageAndReconnect(t *testing.T) {
+	var calls int
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var req geminiRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		if calls == 1 {
+			fmt.Fprintf(w, "data: %s\n\n", `{"candidates":[{"content":{"parts":[{"text":"<html>"}]}}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":1}}`)
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+
+		n := len(req.Contents)
+		if n < 2 || req.Contents[n-2].Role != "model" || req.Contents[n-2].Parts[0].Text != "<html>" || req.Contents[n-1].Parts[0].Text != streamContinuePrompt {
+			t.Errorf("reconnect request missing prefill: %+v", req.Contents)
+		}
+		fmt.Fprintf(w, "data: %s\n\n", `{"candidates":[{"content":{"parts":[{"text":"</html>"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":8,"candidatesTokenCount":2}}`)
+	})
+
+	var usage Usage
+	client := NewGeminiClient("test-key", "gemini-2.5-flash", WithBaseURL(server.URL),
+		WithStreamReconnect(1), WithStreamUsage(func(u Usage) { usage = u }))
+
+	var out strings.Builder
+	if err := client.GenerateCodeStream(context.Background(), "hello", &out); err != nil {
+		t.Fatalf("GenerateCodeStream failed: %v", err)
+	}
+	if out.String() != "<html></html>" || calls != 2 {
+		t.Errorf("output=%q calls=%d", out.String(), calls)
+	}
+	if usage != (Usage{PromptTokens: 13, CompletionTokens: 3}) {
+		t.Errorf("usage = %+v", usage)
+	}
+}
+
+func TestGeminiClient_StreamTruncated(t *testing.T) {
+	var calls int
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "text/event-stream")
+		if calls == 1 {
+			// Ends cleanly, but without a finishReason.
+			fmt.Fprintf(w, "data: %s\n\n", geminiChunk("<html>"))
+			return
+		}
+		fmt.Fprintf(w, "data: %s\n\n", `{"candidates":[{"content":{"parts":[{"text":"</html>"}]},"finishReason":"STOP"}]}`)
+	})
+
+	client := NewGeminiClient("test-key", "gemini-2.5-flash", WithBaseURL(server.URL))
+	var out strings.Builder
+	if err := client.GenerateCodeStream(context.Background(), "hello", &out); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected a truncated stream error, got %v", err)
+	}
+
+	calls = 0
+	out.Reset()
+	client = NewGeminiClient("test-key", "gemini-2.5-flash", WithBaseURL(server.URL), WithStreamReconnect(1))
+	if err := client.GenerateCodeStream(context.Background(), "hello", &out); err != nil {
+		t.Fatalf("GenerateCodeStream failed: %v", err)
+	}
+	if out.String() != "<html></html>" || calls != 2 {
+		t.Errorf("output=%q calls=%d", out.String(), calls)
+	}
+}
+
+func TestGeminiClient_RedactsAPIKey(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeGeminiResponse(w, "ok")
+	})
+
+	var logs, trace bytes.Buffer
+	client := NewGeminiClient("goog-secret-key", "gemini-2.5-flash",
+		WithBaseURL(server.URL),
+		WithLogger(slog.New(slog.NewJSONHandler(&logs, nil))),
+		WithDumpRequests(true),
+		WithHTTPTrace(&trace),
+	)
+	if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+
+	if !strings.Contains(trace.String(), "X-Goog-Api-Key: [redacted]") {
+		t.Errorf("trace missing redacted key header:\n%s", trace.String())
+	}
+	for name, out := range map[string]string{"dump": logs.String(), "trace": trace.String()} {
+		if strings.Contains(out, "goog-secret-key") {
+			t.Errorf("%s leaked the API key:\n%s", name, out)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Package headless validates generated pages in headless Chrome. It lives
+// in its own package so that only programs using it depend on chromedp;
+// pass a Validator to llm.WithHeadlessValidation.
+package headless
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+const defaultSettle = 250 * time.Millisecond
+
+// ErrConsoleErrors is returned when a page logs console errors or throws
+// uncaught exceptions while loading.
+var ErrConsoleErrors = errors.New("page raised console errors")
+
+// Validator loads HTML in a fresh headless Chrome tab and fails if it
+// throws an uncaught exception or calls console.error during load or the
+// Settle period after it. Chrome must be installed.
+type Validator struct {
+	// Settle is how long to keep listening after the load event, to catch
+	// errors from timers and promise callbacks. Zero uses 250ms.
+	Settle time.Duration
+	// AllocatorOptions are passed to chromedp.NewExecAllocator after the
+	// default headless options, e.g. to set the Chrome binary path.
+	AllocatorOptions []chromedp.ExecAllocatorOption
+}
+
+func New() *Validator {
+	return &Validator{}
+}
+
+// Validate serves html from a loopback listener, so relative URLs and
+// the page's origin behave as they would when hosted, and loads it.
+func (v *Validator) Validate(ctx context.Context, html string) error {
+	url, stop, err := serve(html)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	opts := append(chromedp.DefaultExecAllocatorOptions[:], v.AllocatorOptions...)
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, opts...)
+	defer cancelAlloc()
+	tabCtx, cancelTab := chromedp.NewContext(allocCtx)
+	defer cancelTab()
+
+	var mu sync.Mutex
+	var problems []string
+	chromedp.ListenTarget(tabCtx, func(ev any) {
+		var msg string
+		switch ev := ev.(type) {
+		case *runtime.EventExceptionThrown:
+			msg = ev.ExceptionDetails.Error()
+		case *runtime.EventConsoleAPICalled:
+			if ev.Type != runtime.APITypeError {
+				return
+			}
+			msg = "console.error: " + consoleArgs(ev.Args)
+		default:
+			return
+		}
+		mu.Lock()
+		problems = append(problems, msg)
+		mu.Unlock()
+	})
+
+	settle := v.Settle
+	if settle <= 0 {
+		settle = defaultSettle
+	}
+	if err := chromedp.Run(tabCtx, chromedp.Navigate(url), chromedp.Sleep(settle)); err != nil {
+		return fmt.Errorf("failed to load page in headless browser: %w", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrConsoleErrors, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// serve starts an HTTP server on a loopback port answering every request
+// with html, and returns its URL and a function that shuts it down.
+func serve(html string) (string, func(), error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(html))
+	})}
+	go srv.Serve(ln)
+
+	return "http://" + ln.Addr().String() + "/", func() { srv.Close() }, nil
+}
+
+func consoleArgs(args []*runtime.RemoteObject) string {
+	parts := make([]string, 0, len(args))
+	for _, a := range args {
+		switch {
+		case a.Description != "":
+			parts = append(parts, a.Description)
+		case len(a.Value) > 0:
+			parts = append(parts, strings.Trim(string(a.Value), `"`))
+		default:
+			parts = append(parts, string(a.Type))
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,53 @@
+package headless
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func requireChrome(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{"google-chrome", "chromium", "chromium-browser", "headless-shell"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return
+		}
+	}
+	t.Skip("Chrome is not installed")
+}
+
+func TestValidator(t *testing.T) {
+	requireChrome(t)
+
+	tests := []struct {
+		name    string
+		html    string
+		wantErr string
+	}{
+		{name: "clean", html: "<!DOCTYPE html><html><body><script>document.body.textContent = 'ok'</script></body></html>"},
+		{name: "uncaught exception", html: "<html><body><script>missing()</script></body></html>", wantErr: "missing"},
+		{name: "console error", html: "<html><body><script>console.error('broken', 42)</script></body></html>", wantErr: "broken 42"},
+		{name: "async error", html: "<html><body><script>setTimeout(() => { throw new Error('later') }, 10)</script></body></html>", wantErr: "later"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			err := New().Validate(ctx, tt.html)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrConsoleErrors) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected ErrConsoleErrors mentioning %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
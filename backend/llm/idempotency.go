@@ -0,0 +1,42 @@
+package llm
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+)
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey returns a context that makes client calls using it
+// derive their Idempotency-Key headers from key, e.g. a job ID so a worker
+// retrying the job doesn't get billed twice. A call can send several
+// requests (moderation, tool-loop rounds, stream reconnects), so each gets
+// key suffixed with a hash of its method, URL and body: retries of the same
+// request share a key, different requests don't. Without a key each
+// request gets a random UUID, reused across its retries.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// requestIdempotencyKey returns the Idempotency-Key for a request.
+func requestIdempotencyKey(ctx context.Context, method, url string, body []byte) string {
+	key, ok := ctx.Value(idempotencyKey{}).(string)
+	if !ok || key == "" {
+		return newUUID()
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", method, url)
+	h.Write(body)
+	return fmt.Sprintf("%s:%x", key, h.Sum(nil)[:8])
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIClient_IdempotencyKeyReusedAcrossRetries(t *testing.T) {
+	var keys []string
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if len(keys) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writeChatResponse(w, "ok")
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithRetries(2), WithBackoff(0, 0))
+
+	ctx := WithIdempotencyKey(context.Background(), "job-42")
+	if _, err := client.GenerateCode(ctx, "hello"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	if len(keys) != 3 || !strings.HasPrefix(keys[0], "job-42:") || keys[1] != keys[0] || keys[2] != keys[0] {
+		t.Errorf("expected one job-42 key on every attempt, got %q", keys)
+	}
+}
+
+func TestOpenAIClient_IdempotencyKeyPerToolRound(t *testing.T) {
+	var keys []string
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		w.Header().Set("Content-Type", "application/json")
+		if len(keys) == 1 {
+			w.Write([]byte(`{"choices":[{"finish_reason":"tool_calls","message":{"role":"assistant","tool_calls":[{"id":"call_1","type":"function","function":{"name":"now","arguments":"{}"}}]}}]}`))
+			return
+		}
+		writeChatResponse(w, "ok")
+	})
+	tools := []Tool{{Name: "now", Parameters: json.RawMessage(`{"type":"object"}`)}}
+	handlers := map[string]ToolHandler{
+		"now": func(ctx context.Context, arguments string) (string, error) { return "noon", nil },
+	}
+	client := NewOpenAIClient("test-key", "gpt-4o", WithBaseURL(server.URL), WithTools(tools, handlers))
+
+	ctx := WithIdempotencyKey(context.Background(), "job-42")
+	if _, err := client.GenerateCode(ctx, "hello"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	if len(keys) != 2 || keys[0] == keys[1] {
+		t.Fatalf("tool rounds should get distinct keys, got %q", keys)
+	}
+	for _, k := range keys {
+		if !strings.HasPrefix(k, "job-42:") {
+			t.Errorf("key %q not derived from job-42", k)
+		}
+	}
+}
+
+func TestOpenAIClient_IdempotencyKeyGenerated(t *testing.T) {
+	var keys []string
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		writeChatResponse(w, "ok")
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+			t.Fatalf("GenerateCode failed: %v", err)
+		}
+	}
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	for _, k := range keys {
+		if !uuid.MatchString(k) {
+			t.Errorf("key %q is not a UUIDv4", k)
+		}
+	}
+	if keys[0] == keys[1] {
+		t.Error("separate calls should get distinct keys")
+	}
+}
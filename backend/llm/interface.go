@@ -1,7 +1,37 @@
 package llm
 
-import "context"
+import (
+	"context"
+	"io"
+)
 
 type Provider interface {
 	GenerateCode(ctx context.Context, prompt string) (string, error)
 }
+
+// StreamProvider is implemented by providers that can stream output as it
+// is generated.
+type StreamProvider interface {
+	GenerateCodeStream(ctx context.Context, prompt string, w io.Writer) error
+}
+
+// ResultProvider is implemented by providers that can return the full
+// GenerationResult, including token usage.
+type ResultProvider interface {
+	Generate(ctx context.Context, prompt string) (*GenerationResult, error)
+}
+
+type Role string
+
+const (
+	RoleSystem    Role = "system"
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+)
+
+// Message is the provider-neutral chat message. Each provider translates
+// messages into its own wire format.
+type Message struct {
+	Role    Role
+	Content string
+}
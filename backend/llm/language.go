@@ -0,0 +1,26 @@
+package llm
+
+import (
+	"context"
+	"strings"
+)
+
+type languageKey struct{}
+
+// WithLanguage returns a context that makes client calls using it tell the
+// model to write all user-facing text in lang, e.g. "German" or "pt-BR".
+// The instruction goes at the end of the system prompt, or of the user
+// prompt when the system prompt is disabled.
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, languageKey{}, lang)
+}
+
+// languageDirective returns the localization instruction for ctx, or ""
+// if it has no language.
+func languageDirective(ctx context.Context) string {
+	lang, _ := ctx.Value(languageKey{}).(string)
+	if lang = strings.TrimSpace(lang); lang == "" {
+		return ""
+	}
+	return "All user-facing text must be in " + lang + "."
+}
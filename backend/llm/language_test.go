@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestWithLanguage(t *testing.T) {
+	ctx := WithLanguage(context.Background(), "German")
+	want := "All user-facing text must be in German."
+
+	opts := defaultOptions()
+	messages, err := opts.buildMessages(ctx, "a todo app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(messages[0].Content, "\n\n"+want) || messages[1].Content != "a todo app" {
+		t.Errorf("unexpected messages %+v", messages)
+	}
+
+	WithDisableSystemPrompt()(&opts)
+	messages, _ = opts.buildMessages(ctx, "a todo app")
+	if len(messages) != 1 || messages[0].Content != "a todo app\n\n"+want {
+		t.Errorf("expected the instruction on the user prompt, got %+v", messages)
+	}
+
+	plain := defaultOptions()
+	for _, ctx := range []context.Context{context.Background(), WithLanguage(context.Background(), " ")} {
+		if messages, _ := plain.buildMessages(ctx, "a todo app"); strings.Contains(messages[0].Content, "user-facing text") {
+			t.Errorf("unexpected instruction without a language: %q", messages[0].Content)
+		}
+	}
+}
+
+func TestAnthropicClient_WithLanguage(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req anthropicRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if !strings.HasSuffix(req.System, "All user-facing text must be in Japanese.") {
+			t.Errorf("system prompt missing the language: %q", req.System)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(anthropicResponse{
+			Content:    []anthropicContentBlock{{Type: "text", Text: "<html></html>"}},
+			StopReason: "end_turn",
+		})
+	})
+	client := NewAnthropicClient("test-key", "", WithBaseURL(server.URL))
+
+	if _, err := client.GenerateCode(WithLanguage(context.Background(), "Japanese"), "hello"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+}
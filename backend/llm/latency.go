@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+const defaultLatencySamples = 1024
+
+// LatencyTrackingProvider records how long successful GenerateCode calls
+// take, for in-process latency stats. It keeps a uniform random sample of
+// at most a fixed number of durations, so memory stays bounded however many
+// calls it sees.
+type LatencyTrackingProvider struct {
+	provider Provider
+
+	mu      sync.Mutex
+	samples []time.Duration
+	size    int
+	seen    int
+}
+
+// NewLatencyTrackingProvider keeps up to size samples; a non-positive size
+// uses 1024.
+func NewLatencyTrackingProvider(p Provider, size int) *LatencyTrackingProvider {
+	if size <= 0 {
+		size = defaultLatencySamples
+	}
+	return &LatencyTrackingProvider{provider: p, size: size}
+}
+
+func (l *LatencyTrackingProvider) GenerateCode(ctx context.Context, prompt string) (string, error) {
+	start := time.Now()
+	code, err := l.provider.GenerateCode(ctx, prompt)
+	if err == nil {
+		l.record(time.Since(start))
+	}
+	return code, err
+}
+
+// record adds d to the reservoir (Algorithm R).
+func (l *LatencyTrackingProvider) record(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.seen++
+	if len(l.samples) < l.size {
+		l.samples = append(l.samples, d)
+		return
+	}
+	if i := rand.IntN(l.seen); i < l.size {
+		l.samples[i] = d
+	}
+}
+
+// Percentiles returns the p50, p90 and p99 latencies, keyed 0.5, 0.9 and
+// 0.99. It is empty until a call has succeeded.
+func (l *LatencyTrackingProvider) Percentiles() map[float64]time.Duration {
+	l.mu.Lock()
+	sorted := slices.Clone(l.samples)
+	l.mu.Unlock()
+
+	out := make(map[float64]time.Duration)
+	if len(sorted) == 0 {
+		return out
+	}
+	slices.Sort(sorted)
+	for _, p := range []float64{0.5, 0.9, 0.99} {
+		// Nearest rank.
+		rank := int(math.Ceil(p * float64(len(sorted))))
+		out[p] = sorted[rank-1]
+	}
+	return out
+}
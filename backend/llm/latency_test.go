@@ -0,0 +1,62 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type failingProvider struct{}
+
+func (failingProvider) GenerateCode(ctx context.Context, prompt string) (string, error) {
+	return "", errors.New("boom")
+}
+
+func TestLatencyTrackingProvider_Percentiles(t *testing.T) {
+	l := NewLatencyTrackingProvider(staticProvider("ok"), 0)
+	if got := l.Percentiles(); len(got) != 0 {
+		t.Fatalf("expected no percentiles before any call, got %v", got)
+	}
+
+	for i := 1; i <= 100; i++ {
+		l.record(time.Duration(i) * time.Millisecond)
+	}
+
+	want := map[float64]time.Duration{0.5: 50 * time.Millisecond, 0.9: 90 * time.Millisecond, 0.99: 99 * time.Millisecond}
+	got := l.Percentiles()
+	for p, d := range want {
+		if got[p] != d {
+			t.Errorf("p%v = %v, want %v", p*100, got[p], d)
+		}
+	}
+}
+
+func TestLatencyTrackingProvider_BoundedSamples(t *testing.T) {
+	l := NewLatencyTrackingProvider(staticProvider("ok"), 10)
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Go(func() {
+			if _, err := l.GenerateCode(context.Background(), "hello"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if len(l.samples) != 10 || l.seen != 50 {
+		t.Errorf("samples=%d seen=%d", len(l.samples), l.seen)
+	}
+}
+
+func TestLatencyTrackingProvider_SkipsErrors(t *testing.T) {
+	l := NewLatencyTrackingProvider(failingProvider{}, 0)
+	if _, err := l.GenerateCode(context.Background(), "hello"); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := l.Percentiles(); len(got) != 0 {
+		t.Errorf("failed call was recorded: %v", got)
+	}
+}
@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// lifecycle tracks a client's in-flight calls so Close and Shutdown can
+// cancel them.
+type lifecycle struct {
+	mu       sync.Mutex
+	closed   bool
+	root     context.Context
+	cancel   context.CancelCauseFunc
+	inflight sync.WaitGroup
+}
+
+func newLifecycle() *lifecycle {
+	root, cancel := context.WithCancelCause(context.Background())
+	return &lifecycle{root: root, cancel: cancel}
+}
+
+// begin registers a call, failing with ErrClosed once the client is
+// closed. The returned context is also canceled by Close. end must be
+// called with the call's error when it finishes; it marks errors caused by
+// Close as ErrClosed.
+func (l *lifecycle) begin(ctx context.Context) (context.Context, func(error) error, error) {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil, nil, ErrClosed
+	}
+	l.inflight.Add(1)
+	l.mu.Unlock()
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(l.root, func() { cancel(ErrClosed) })
+
+	end := func(err error) error {
+		stop()
+		if err != nil && errors.Is(context.Cause(ctx), ErrClosed) {
+			err = fmt.Errorf("%w: %w", ErrClosed, err)
+		}
+		cancel(nil)
+		l.inflight.Done()
+		return err
+	}
+	return ctx, end, nil
+}
+
+func (l *lifecycle) close() {
+	l.mu.Lock()
+	l.closed = true
+	l.mu.Unlock()
+	l.cancel(ErrClosed)
+}
+
+func (l *lifecycle) shutdown(ctx context.Context) error {
+	l.mu.Lock()
+	l.closed = true
+	l.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		l.inflight.Wait()
+		close(drained)
+	}()
+
+	defer l.cancel(ErrClosed)
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close cancels all in-flight calls, which fail with ErrClosed, as do any
+// made afterwards, and closes the client's idle connections. The client
+// runs no goroutines of its own beyond those of calls in flight, which end
+// with them. Close is safe to call more than once.
+func (o *OpenAIClient) Close() error {
+	o.life.close()
+	o.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// Shutdown stops accepting calls and waits for those in flight to finish,
+// then closes the idle connections. If ctx ends first, the remaining calls
+// are canceled and ctx's error is returned.
+func (o *OpenAIClient) Shutdown(ctx context.Context) error {
+	defer o.httpClient.CloseIdleConnections()
+	return o.life.shutdown(ctx)
+}
+
+// Close cancels all in-flight calls, see OpenAIClient.Close.
+func (a *AnthropicClient) Close() error {
+	a.life.close()
+	a.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// Shutdown drains in-flight calls, see OpenAIClient.Shutdown.
+func (a *AnthropicClient) Shutdown(ctx context.Context) error {
+	defer a.httpClient.CloseIdleConnections()
+	return a.life.shutdown(ctx)
+}
+
+// Close cancels all in-flight calls, see OpenAIClient.Close.
+func (g *GeminiClient) Close() error {
+	g.life.close()
+	g.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// Shutdown drains in-flight calls, see OpenAIClient.Shutdown.
+func (g *GeminiClient) Shutdown(ctx context.Context) error {
+	defer g.httpClient.CloseIdleConnections()
+	return g.life.shutdown(ctx)
+}
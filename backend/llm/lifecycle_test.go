@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOpenAIClient_Close(t *testing.T) {
+	started := make(chan struct{}, 1)
+	done := make(chan struct{})
+	defer close(done)
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := client.GenerateCode(context.Background(), "hello")
+		errc <- err
+	}()
+
+	<-started
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("in-flight call: expected ErrClosed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("in-flight call was not canceled")
+	}
+
+	if _, err := client.GenerateCode(context.Background(), "hello"); !errors.Is(err, ErrClosed) {
+		t.Errorf("call after Close: expected ErrClosed, got %v", err)
+	}
+}
+
+func TestOpenAIClient_ShutdownDrains(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		writeChatResponse(w, "ok")
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := client.GenerateCode(context.Background(), "hello")
+		errc <- err
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- client.Shutdown(context.Background()) }()
+
+	// New calls are refused while draining.
+	time.Sleep(10 * time.Millisecond)
+	if _, err := client.GenerateCode(context.Background(), "hello"); !errors.Is(err, ErrClosed) {
+		t.Errorf("call during Shutdown: expected ErrClosed, got %v", err)
+	}
+
+	close(release)
+	if err := <-errc; err != nil {
+		t.Errorf("in-flight call should complete, got %v", err)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+}
+
+func TestOpenAIClient_ShutdownDeadline(t *testing.T) {
+	started := make(chan struct{}, 1)
+	done := make(chan struct{})
+	defer close(done)
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := client.GenerateCode(context.Background(), "hello")
+		errc <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+	if err := <-errc; !errors.Is(err, ErrClosed) {
+		t.Errorf("remaining call: expected ErrClosed, got %v", err)
+	}
+}
+
+func TestOpenAIClient_CloseIdleConnections(t *testing.T) {
+	closed := make(chan struct{}, 1)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeChatResponse(w, "ok")
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			select {
+			case closed <- struct{}{}:
+			default:
+			}
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+	if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("idle connection was not closed")
+	}
+	if err := client.Close(); err != nil {
+		t.Errorf("second Close failed: %v", err)
+	}
+}
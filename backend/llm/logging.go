@@ -0,0 +1,139 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// redactMaxLen is how much of a prompt or response DefaultRedactor keeps.
+const redactMaxLen = 200
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	tokenPattern = regexp.MustCompile(`\b(?:sk|pk|rk|key|ghp|xox[bap])[-_][A-Za-z0-9_-]{8,}|\bBearer\s+[A-Za-z0-9._~+/-]+=*|\b[A-Za-z0-9_-]{32,}\b`)
+)
+
+// WithLogger logs each request and response at debug level, and failures
+// at warn level. Prompt and response text pass through the redactor first.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithRedactor replaces DefaultRedactor for prompt and response text in
+// logs. Pass an identity function to log full text.
+func WithRedactor(redact func(string) string) Option {
+	return func(o *options) {
+		o.redactor = redact
+	}
+}
+
+// WithDumpRequests logs the method, URL, headers (secrets redacted) and
+// pretty-printed JSON body of every request before it is sent. It uses the
+// WithLogger logger, or slog.Default() if none is set.
+func WithDumpRequests(enabled bool) Option {
+	return func(o *options) {
+		o.dumpRequests = enabled
+	}
+}
+
+// DefaultRedactor masks email addresses and API-key-looking strings and
+// truncates the text to 200 characters.
+func DefaultRedactor(s string) string {
+	s = emailPattern.ReplaceAllString(s, "[email]")
+	s = tokenPattern.ReplaceAllString(s, "[token]")
+
+	if runes := []rune(s); len(runes) > redactMaxLen {
+		s = fmt.Sprintf("%s...(%d more chars)", string(runes[:redactMaxLen]), len(runes)-redactMaxLen)
+	}
+	return s
+}
+
+func (o *options) redact(s string) string {
+	if o.redactor == nil {
+		return s
+	}
+	return o.redactor(s)
+}
+
+func (o *options) logRequest(ctx context.Context, model, prompt string) {
+	if o.logger == nil {
+		return
+	}
+	o.logger.DebugContext(ctx, "llm request", "model", model, "prompt", o.redact(prompt))
+}
+
+func (o *options) logResponse(ctx context.Context, model, content string, elapsed time.Duration, err error) {
+	if o.logger == nil {
+		return
+	}
+	if err != nil {
+		o.logger.WarnContext(ctx, "llm request failed", "model", model, "duration", elapsed, "error", err)
+		return
+	}
+	o.logger.DebugContext(ctx, "llm response", "model", model, "duration", elapsed, "response", o.redact(content))
+}
+
+func (o *options) logStreamEnd(ctx context.Context, model string, elapsed time.Duration, err error) {
+	if o.logger == nil {
+		return
+	}
+	if err != nil {
+		o.logger.WarnContext(ctx, "llm stream failed", "model", model, "duration", elapsed, "error", err)
+		return
+	}
+	o.logger.DebugContext(ctx, "llm stream finished", "model", model, "duration", elapsed)
+}
+
+// secretHeaders are masked in request dumps.
+var secretHeaders = map[string]bool{
+	"Authorization":       true,
+	"Api-Key":             true,
+	"X-Api-Key":           true,
+	"X-Goog-Api-Key":      true,
+	"Proxy-Authorization": true,
+}
+
+func redactHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		if secretHeaders[http.CanonicalHeaderKey(name)] {
+			out[name] = "[redacted]"
+			continue
+		}
+		out[name] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+func (o *options) dumpRequest(ctx context.Context, req *http.Request, body []byte) {
+	if !o.dumpRequests {
+		return
+	}
+
+	logger := o.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, body, "", "  "); err != nil {
+		pretty.Reset()
+		pretty.Write(body)
+	}
+
+	logger.InfoContext(ctx, "llm request dump",
+		"method", req.Method,
+		"url", req.URL.String(),
+		"headers", redactHeaders(req.Header),
+		"body", pretty.String(),
+	)
+}
@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDefaultRedactor(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "make a todo app", "make a todo app"},
+		{"email", "send it to jane.doe@example.com please", "send it to [email] please"},
+		{"openai key", "my key is sk-proj-abcdefgh12345678", "my key is [token]"},
+		{"bearer", "Authorization: Bearer eyJhbGciOi.J9.abc", "Authorization: [token]"},
+		{"long opaque", "id 0123456789abcdef0123456789abcdef end", "id [token] end"},
+	}
+
+	for _, tt := range tests {
+		if got := DefaultRedactor(tt.in); got != tt.want {
+			t.Errorf("%s: DefaultRedactor(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+
+	long := DefaultRedactor(strings.Repeat("a ", 300))
+	if !strings.HasSuffix(long, "...(400 more chars)") {
+		t.Errorf("long text not truncated: %q", long)
+	}
+}
+
+func TestOpenAIClient_LogsRedactedText(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeChatResponse(w, "contact admin@example.com")
+	})
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithLogger(logger))
+
+	if _, err := client.GenerateCode(context.Background(), "page for bob@example.com"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+
+	logs := buf.String()
+	if strings.Contains(logs, "@example.com") {
+		t.Errorf("logs contain unredacted email:\n%s", logs)
+	}
+	if !strings.Contains(logs, "llm request") || !strings.Contains(logs, "llm response") {
+		t.Errorf("missing request/response log lines:\n%s", logs)
+	}
+}
+
+func TestOpenAIClient_CustomRedactor(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeChatResponse(w, "ok")
+	})
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := NewOpenAIClient("test-key", "gpt-4",
+		WithBaseURL(server.URL),
+		WithLogger(logger),
+		WithRedactor(func(string) string { return "<hidden>" }),
+	)
+
+	if _, err := client.GenerateCode(context.Background(), "secret prompt"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "secret prompt") || !strings.Contains(buf.String(), "<hidden>") {
+		t.Errorf("custom redactor not applied:\n%s", buf.String())
+	}
+}
+
+func TestOpenAIClient_DumpRequests(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeChatResponse(w, "ok")
+	})
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	client := NewOpenAIClient("sk-secret-key", "gpt-4",
+		WithBaseURL(server.URL),
+		WithLogger(logger),
+		WithDumpRequests(true),
+		WithRequestCompression(true),
+	)
+
+	if _, err := client.GenerateCode(context.Background(), strings.Repeat("long prompt ", 200)); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+
+	var entry struct {
+		Msg     string            `json:"msg"`
+		Method  string            `json:"method"`
+		URL     string            `json:"url"`
+		Headers map[string]string `json:"headers"`
+		Body    string            `json:"body"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to parse dump log %q: %v", buf.String(), err)
+	}
+
+	if entry.Msg != "llm request dump" || entry.Method != "POST" || entry.URL != server.URL+"/chat/completions" {
+		t.Errorf("unexpected dump entry: %+v", entry)
+	}
+	if entry.Headers["Authorization"] != "[redacted]" {
+		t.Errorf("authorization header not redacted: %v", entry.Headers)
+	}
+	if strings.Contains(buf.String(), "sk-secret-key") {
+		t.Error("dump leaked the api key")
+	}
+	if !strings.Contains(entry.Body, "\n  \"model\": \"gpt-4\"") {
+		t.Errorf("body not pretty-printed JSON: %q", entry.Body)
+	}
+}
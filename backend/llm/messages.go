@@ -0,0 +1,142 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const systemPrompt = `You are a code generator that creates single-file web applications.
+
+Rules:
+- Generate complete, working HTML including inline CSS and JavaScript
+- Use modern JavaScript (ES6+)
+- Make it visually appealing with good CSS styling
+- Include all code in one HTML file
+- Be creative and functional
+- Do not include explanations, only code
+
+You MUST format your response with the code inside a markdown code block like this:
+
+` + "```html" + `
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        /* CSS here */
+    </style>
+</head>
+<body>
+    <!-- HTML here -->
+    <script>
+        // JavaScript here
+    </script>
+</body>
+</html>
+` + "```" + `
+
+Important: Only return the code block, no additional text before or after.`
+
+// PromptPreProcessor rewrites the user prompt before a request is built,
+// e.g. to prepend project context.
+type PromptPreProcessor func(prompt string) (string, error)
+
+// WithPromptPreProcessor adds pre-processors that run in order on every
+// prompt, before the token size check.
+func WithPromptPreProcessor(fns ...PromptPreProcessor) Option {
+	return func(o *options) {
+		o.preProcessors = append(o.preProcessors, fns...)
+	}
+}
+
+// WithCaptureSentMessages records the messages each request actually sent
+// in GenerationResult.SentMessages, for debugging prompt processing.
+func WithCaptureSentMessages(enabled bool) Option {
+	return func(o *options) {
+		o.captureSentMessages = enabled
+	}
+}
+
+// WithSystemPrompt replaces the default system prompt, which asks for a
+// single-file web app.
+func WithSystemPrompt(prompt string) Option {
+	return func(o *options) {
+		o.systemPrompt = prompt
+	}
+}
+
+// WithSystemPromptFunc computes the system prompt on every call from the
+// request context, e.g. to enable directives for beta users. It takes
+// precedence over WithSystemPrompt.
+func WithSystemPromptFunc(fn func(ctx context.Context) string) Option {
+	return func(o *options) {
+		o.systemPromptFunc = fn
+	}
+}
+
+// WithCurrentTime adds today's date, from the configured Clock, to the
+// system prompt so the model uses the right year in copyright notices and
+// date defaults.
+func WithCurrentTime(enabled bool) Option {
+	return func(o *options) {
+		o.currentTime = enabled
+	}
+}
+
+// WithAllowEmptyPrompt sends blank prompts instead of rejecting them with
+// ErrEmptyPrompt, for generation driven by the system prompt alone.
+func WithAllowEmptyPrompt() Option {
+	return func(o *options) {
+		o.allowEmptyPrompt = true
+	}
+}
+
+// buildMessages runs prompt through the pre-processors and pairs it with
+// the system prompt.
+func (o *options) buildMessages(ctx context.Context, prompt string) ([]Message, error) {
+	system := systemPrompt
+	if o.systemPromptFunc != nil {
+		system = o.systemPromptFunc(ctx)
+	} else if o.systemPrompt != "" {
+		system = o.systemPrompt
+	}
+	return o.buildMessagesWith(ctx, system, prompt)
+}
+
+// buildMessagesWith is buildMessages with a different system prompt.
+func (o *options) buildMessagesWith(ctx context.Context, system, prompt string) ([]Message, error) {
+	if !o.allowEmptyPrompt && strings.TrimSpace(prompt) == "" {
+		return nil, ErrEmptyPrompt
+	}
+
+	for _, pre := range o.preProcessors {
+		var err error
+		if prompt, err = pre(prompt); err != nil {
+			return nil, fmt.Errorf("failed to pre-process prompt: %w", err)
+		}
+	}
+	if o.sanitizePrompt {
+		prompt = SanitizePrompt(prompt)
+	}
+
+	var messages []Message
+	if !o.disableSystemPrompt {
+		if directives := o.qualityProfile.Directives(); directives != "" {
+			system += "\n\n" + directives
+		}
+		if o.sanitizePrompt {
+			system += "\n\n" + sanitizeDirective
+		}
+		if o.currentTime {
+			system += "\n\nToday's date is " + clockOrReal(o.clock).Now().Format("January 2, 2006") + "."
+		}
+		if directive := languageDirective(ctx); directive != "" {
+			system += "\n\n" + directive
+		}
+		messages = append(messages, Message{Role: RoleSystem, Content: system})
+	} else if directive := languageDirective(ctx); directive != "" {
+		prompt += "\n\n" + directive
+	}
+
+	return append(messages, Message{Role: RoleUser, Content: prompt}), nil
+}
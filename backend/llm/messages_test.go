@@ -0,0 +1,161 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithPromptPreProcessor(t *testing.T) {
+	var got string
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req openAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		got = req.Messages[len(req.Messages)-1].Content
+		writeChatResponse(w, "ok")
+	})
+
+	styleGuide := func(p string) (string, error) { return "Style guide: use tabs.\n\n" + p, nil }
+	files := func(p string) (string, error) { return "File main.js: ...\n" + p, nil }
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithPromptPreProcessor(styleGuide, files))
+
+	if _, err := client.GenerateCode(context.Background(), "add a button"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	if want := "File main.js: ...\nStyle guide: use tabs.\n\nadd a button"; got != want {
+		t.Errorf("prompt = %q, want %q", got, want)
+	}
+}
+
+func TestWithPromptPreProcessor_Errors(t *testing.T) {
+	boom := errors.New("context file missing")
+	client := NewOpenAIClient("test-key", "gpt-4", WithPromptPreProcessor(func(string) (string, error) {
+		return "", boom
+	}))
+
+	if _, err := client.GenerateCode(context.Background(), "hi"); !errors.Is(err, boom) {
+		t.Fatalf("expected pre-processor error, got %v", err)
+	}
+}
+
+func TestWithPromptPreProcessor_RunsBeforeSizeCheck(t *testing.T) {
+	bigContext := func(p string) (string, error) { return strings.Repeat("context ", 500) + p, nil }
+	client := NewOpenAIClient("test-key", "gpt-4", WithPromptPreProcessor(bigContext), WithMaxPromptTokens(100))
+
+	if _, err := client.GenerateCode(context.Background(), "hi"); !errors.Is(err, ErrPromptTooLarge) {
+		t.Fatalf("expected ErrPromptTooLarge, got %v", err)
+	}
+}
+
+func TestWithCaptureSentMessages(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeChatResponse(w, "ok")
+	})
+	addContext := func(p string) (string, error) { return "Context: none.\n" + p, nil }
+
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithPromptPreProcessor(addContext), WithCaptureSentMessages(true))
+	result, err := client.Generate(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	want := []Message{
+		{Role: RoleSystem, Content: systemPrompt},
+		{Role: RoleUser, Content: "Context: none.\nhello"},
+	}
+	if len(result.SentMessages) != len(want) || result.SentMessages[0] != want[0] || result.SentMessages[1] != want[1] {
+		t.Errorf("SentMessages = %+v", result.SentMessages)
+	}
+
+	plain := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+	if result, _ := plain.Generate(context.Background(), "hello"); result.SentMessages != nil {
+		t.Error("messages should not be captured by default")
+	}
+}
+
+func TestAnthropicClient_CaptureSentMessages(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeAnthropicResponse(w, "ok")
+	})
+
+	client := NewAnthropicClient("test-key", "claude-sonnet-4-20250514", WithBaseURL(server.URL), WithCaptureSentMessages(true))
+	result, err := client.Generate(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(result.SentMessages) != 2 || result.SentMessages[0].Role != RoleSystem || result.SentMessages[1].Content != "hello" {
+		t.Errorf("SentMessages = %+v", result.SentMessages)
+	}
+}
+
+func TestWithCurrentTime(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, time.March, 5, 12, 0, 0, 0, time.UTC)}
+	opts := defaultOptions()
+	WithCurrentTime(true)(&opts)
+	WithClock(clock)(&opts)
+
+	messages, err := opts.buildMessages(context.Background(), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(messages[0].Content, "\n\nToday's date is March 5, 2026.") {
+		t.Errorf("system prompt missing date: %q", messages[0].Content)
+	}
+
+	plain := defaultOptions()
+	if messages, _ := plain.buildMessages(context.Background(), "hello"); strings.Contains(messages[0].Content, "Today's date") {
+		t.Error("date should not be added by default")
+	}
+}
+
+func TestEmptyPrompt(t *testing.T) {
+	var calls int
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		writeChatResponse(w, "ok")
+	})
+
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+	if _, err := client.GenerateCode(context.Background(), " \n\t"); !errors.Is(err, ErrEmptyPrompt) {
+		t.Fatalf("expected ErrEmptyPrompt, got %v", err)
+	}
+	if calls != 0 {
+		t.Fatalf("empty prompt was sent")
+	}
+
+	allowed := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithAllowEmptyPrompt())
+	if _, err := allowed.GenerateCode(context.Background(), ""); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d", calls)
+	}
+}
+
+type betaKey struct{}
+
+func TestWithSystemPromptFunc(t *testing.T) {
+	opts := defaultOptions()
+	WithSystemPrompt("static")(&opts)
+	WithSystemPromptFunc(func(ctx context.Context) string {
+		if ctx.Value(betaKey{}) != nil {
+			return "beta"
+		}
+		return "stable"
+	})(&opts)
+
+	messages, err := opts.buildMessages(context.WithValue(context.Background(), betaKey{}, true), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messages[0].Content != "beta" {
+		t.Errorf("system prompt = %q, want beta", messages[0].Content)
+	}
+	if messages, _ := opts.buildMessages(context.Background(), "hello"); messages[0].Content != "stable" {
+		t.Errorf("system prompt = %q, want stable", messages[0].Content)
+	}
+}
@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// ResponseMeta describes the HTTP response a generation came from.
+//
+// Header holds only the headers useful for diagnostics: rate limits
+// (x-ratelimit-*), Retry-After, request IDs and OpenAI's openai-*
+// processing headers. Model is the model the response says served it,
+// which can differ from the one requested when an alias or gateway
+// resolves it.
+type ResponseMeta struct {
+	StatusCode int
+	RequestID  string
+	Model      string
+	Header     http.Header
+}
+
+// newResponseMeta records the status and headers of interest from resp.
+func newResponseMeta(resp *http.Response) *ResponseMeta {
+	meta := &ResponseMeta{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-Request-Id"),
+		Header:     make(http.Header),
+	}
+	for name, values := range resp.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-ratelimit-") || strings.HasPrefix(lower, "openai-") ||
+			lower == "retry-after" || lower == "x-request-id" {
+			meta.Header[name] = values
+		}
+	}
+	return meta
+}
+
+// GenerateCodeMeta is GenerateCode that also returns the metadata of the
+// response the code came from. No extra request is made.
+func (o *OpenAIClient) GenerateCodeMeta(ctx context.Context, prompt string) (string, *ResponseMeta, error) {
+	result, err := o.Generate(ctx, prompt)
+	if err != nil {
+		return "", nil, err
+	}
+	return result.Code, result.Meta, nil
+}
@@ -0,0 +1,38 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestOpenAIClient_GenerateCodeMeta(t *testing.T) {
+	var calls int
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("X-Request-Id", "req_123")
+		w.Header().Set("X-Ratelimit-Remaining-Requests", "99")
+		w.Header().Set("Openai-Processing-Ms", "420")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"model":"gpt-4o-2024-08-06","choices":[{"message":{"role":"assistant","content":"<html></html>"}}]}`))
+	})
+	client := NewOpenAIClient("test-key", "gpt-4o", WithBaseURL(server.URL))
+
+	code, meta, err := client.GenerateCodeMeta(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("GenerateCodeMeta failed: %v", err)
+	}
+	if code != "<html></html>" || calls != 1 {
+		t.Errorf("code=%q calls=%d", code, calls)
+	}
+	if meta.StatusCode != http.StatusOK || meta.RequestID != "req_123" || meta.Model != "gpt-4o-2024-08-06" {
+		t.Errorf("unexpected meta: %+v", meta)
+	}
+	if meta.Header.Get("X-Ratelimit-Remaining-Requests") != "99" || meta.Header.Get("Openai-Processing-Ms") != "420" {
+		t.Errorf("headers of interest missing: %v", meta.Header)
+	}
+	if meta.Header.Get("Set-Cookie") != "" || meta.Header.Get("Content-Type") != "" {
+		t.Errorf("unrelated headers kept: %v", meta.Header)
+	}
+}
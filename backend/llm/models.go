@@ -0,0 +1,129 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// defaultModels is the model each provider uses when none is given.
+var defaultModels = map[string]string{
+	"openai":    "gpt-4",
+	"anthropic": "claude-sonnet-4-20250514",
+	"gemini":    "gemini-2.5-flash",
+	"nim":       "meta/llama-3.1-70b-instruct",
+}
+
+// DefaultModel returns the default model for a provider name, or "" if the
+// provider is unknown.
+func DefaultModel(provider string) string {
+	return defaultModels[strings.ToLower(provider)]
+}
+
+var (
+	aliasMu      sync.RWMutex
+	modelAliases = map[string]string{}
+)
+
+// SetModelAlias makes every client resolve alias, e.g. "fast", to model
+// before sending a request, so the real model can be swapped without
+// touching callers. An empty model removes the alias.
+func SetModelAlias(alias, model string) {
+	aliasMu.Lock()
+	defer aliasMu.Unlock()
+	if model == "" {
+		delete(modelAliases, alias)
+		return
+	}
+	modelAliases[alias] = model
+}
+
+// ResolveModel returns the model name is an alias for, or name itself if it
+// isn't one.
+func ResolveModel(name string) string {
+	aliasMu.RLock()
+	defer aliasMu.RUnlock()
+	if model, ok := modelAliases[name]; ok {
+		return model
+	}
+	return name
+}
+
+// WithModelRouter picks the model for each call from its prompt, e.g. to
+// send short prompts to a cheaper model. An empty result keeps the
+// client's model.
+func WithModelRouter(router func(prompt string) string) Option {
+	return func(o *options) {
+		o.modelRouter = router
+	}
+}
+
+// routeModel returns the model to call for prompt, with aliases resolved.
+func (o *options) routeModel(model, prompt string) string {
+	if o.modelRouter != nil {
+		if routed := o.modelRouter(prompt); routed != "" {
+			model = routed
+		}
+	}
+	return ResolveModel(model)
+}
+
+type modelRecorderKey struct{}
+
+// WithModelRecorder returns a context that makes a client call using it
+// store the model it sent the request to in *model, after routing, alias
+// resolution and any fallback. It is set as soon as the model is chosen,
+// so it is also available when the call fails, and for streams. For
+// Generate the same model is in GenerationResult.Model.
+func WithModelRecorder(ctx context.Context, model *string) context.Context {
+	return context.WithValue(ctx, modelRecorderKey{}, model)
+}
+
+// recordModel stores model for a WithModelRecorder caller.
+func recordModel(ctx context.Context, model string) {
+	if p, ok := ctx.Value(modelRecorderKey{}).(*string); ok {
+		*p = model
+	}
+}
+
+// WithFallbackModel retries a call once with model when the API reports
+// that the requested model doesn't exist, e.g. after a retirement, and logs
+// a warning. The fallback then serves the rest of the call, tool rounds
+// included. It applies to non-streaming OpenAI-compatible calls.
+func WithFallbackModel(model string) Option {
+	return func(o *options) {
+		o.fallbackModel = model
+	}
+}
+
+// isModelNotFound reports whether err is the API rejecting an unknown
+// model, which OpenAI signals with a 404 and code model_not_found and some
+// compatible servers with a 400 "model ... does not exist".
+func isModelNotFound(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || (apiErr.StatusCode != http.StatusNotFound && apiErr.StatusCode != http.StatusBadRequest) {
+		return false
+	}
+	msg := strings.ToLower(apiErr.Message)
+	return apiErr.Code == "model_not_found" || strings.Contains(msg, "model") && strings.Contains(msg, "does not exist")
+}
+
+// postFallback is post that switches request to the fallback model when
+// the API doesn't know its model.
+func (o *OpenAIClient) postFallback(ctx context.Context, request *openAIRequest) ([]byte, *ResponseMeta, error) {
+	body, meta, err := o.post(ctx, *request)
+	fallback := ResolveModel(o.opts.fallbackModel)
+	if o.opts.fallbackModel == "" || request.Model == fallback || !isModelNotFound(err) {
+		return body, meta, err
+	}
+
+	if o.opts.logger != nil {
+		o.opts.logger.WarnContext(ctx, "llm model not found, using fallback",
+			"model", request.Model, "fallback", fallback, "error", err)
+	}
+	request.Model = fallback
+	recordModel(ctx, fallback)
+	return o.post(ctx, *request)
+}
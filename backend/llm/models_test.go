@@ -0,0 +1,188 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/egedolmaci/scaffolder/backend/llm/testutil"
+)
+
+func TestDefaultModel(t *testing.T) {
+	tests := []struct {
+		provider string
+		want     string
+	}{
+		{"openai", "gpt-4"},
+		{"OpenAI", "gpt-4"},
+		{"anthropic", "claude-sonnet-4-20250514"},
+		{"acme", ""},
+	}
+
+	for _, tt := range tests {
+		if got := DefaultModel(tt.provider); got != tt.want {
+			t.Errorf("DefaultModel(%q) = %q, want %q", tt.provider, got, tt.want)
+		}
+	}
+}
+
+func TestConstructorsUseDefaultModel(t *testing.T) {
+	if m := NewOpenAIClient("k", "").model; m != DefaultModel("openai") {
+		t.Errorf("OpenAI default model = %q", m)
+	}
+	if m := NewAnthropicClient("k", "").model; m != DefaultModel("anthropic") {
+		t.Errorf("Anthropic default model = %q", m)
+	}
+}
+
+func TestWithModelRouter(t *testing.T) {
+	var models []string
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req openAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		models = append(models, req.Model)
+		writeChatResponse(w, "ok")
+	})
+
+	router := func(prompt string) string {
+		if len(prompt) < 20 {
+			return "gpt-4o-mini"
+		}
+		return ""
+	}
+	client := NewOpenAIClient("test-key", "gpt-4o", WithBaseURL(server.URL), WithModelRouter(router))
+
+	for _, prompt := range []string{"a button", "a full dashboard with charts and a settings page"} {
+		if _, err := client.GenerateCode(context.Background(), prompt); err != nil {
+			t.Fatalf("GenerateCode failed: %v", err)
+		}
+	}
+
+	if len(models) != 2 || models[0] != "gpt-4o-mini" || models[1] != "gpt-4o" {
+		t.Errorf("models = %q, want [gpt-4o-mini gpt-4o]", models)
+	}
+}
+
+func TestSetModelAlias(t *testing.T) {
+	t.Cleanup(func() {
+		SetModelAlias("fast", "")
+		SetModelAlias("smart", "")
+	})
+	SetModelAlias("fast", "gpt-4o-mini")
+	SetModelAlias("smart", "o3")
+
+	var models []string
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req openAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		models = append(models, req.Model)
+		writeChatResponse(w, "ok")
+	})
+
+	for _, model := range []string{"fast", "smart", "gpt-4"} {
+		client := NewOpenAIClient("test-key", model, WithBaseURL(server.URL))
+		if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+			t.Fatalf("GenerateCode failed: %v", err)
+		}
+	}
+
+	want := []string{"gpt-4o-mini", "o3", "gpt-4"}
+	for i := range want {
+		if i >= len(models) || models[i] != want[i] {
+			t.Fatalf("models = %v, want %v", models, want)
+		}
+	}
+
+	SetModelAlias("fast", "")
+	if got := ResolveModel("fast"); got != "fast" {
+		t.Errorf("removed alias resolved to %q", got)
+	}
+}
+
+func TestWithFallbackModel(t *testing.T) {
+	var models []string
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req openAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		models = append(models, req.Model)
+		if req.Model == "gpt-4-retired" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"message": "The model ` + "`gpt-4-retired`" + ` does not exist or you do not have access to it.", "type": "invalid_request_error", "code": "model_not_found"}}`))
+			return
+		}
+		writeChatResponse(w, "ok")
+	})
+
+	var logs bytes.Buffer
+	client := NewOpenAIClient("test-key", "gpt-4-retired",
+		WithBaseURL(server.URL),
+		WithFallbackModel("gpt-4o"),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+	)
+
+	got, err := client.GenerateCode(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	if got != "ok" || len(models) != 2 || models[1] != "gpt-4o" {
+		t.Errorf("got %q with models %q", got, models)
+	}
+	if !strings.Contains(logs.String(), "level=WARN") || !strings.Contains(logs.String(), "fallback=gpt-4o") {
+		t.Errorf("expected a fallback warning, got %q", logs.String())
+	}
+}
+
+func TestIsModelNotFound(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&APIError{StatusCode: 404, Code: "model_not_found"}, true},
+		{&APIError{StatusCode: 400, Message: "The model foo does not exist"}, true},
+		{&APIError{StatusCode: 404, Message: "not found"}, false},
+		{&APIError{StatusCode: 500, Code: "model_not_found"}, false},
+		{errors.New("model does not exist"), false},
+	}
+
+	for _, tt := range tests {
+		if got := isModelNotFound(tt.err); got != tt.want {
+			t.Errorf("isModelNotFound(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestResolvedModel(t *testing.T) {
+	t.Cleanup(func() { SetModelAlias("cheap", "") })
+	SetModelAlias("cheap", "gpt-4o-mini")
+
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeChatResponse(w, "ok")
+	})
+	router := func(prompt string) string { return "cheap" }
+	client := NewOpenAIClient("test-key", "gpt-4o", WithBaseURL(server.URL), WithModelRouter(router))
+
+	var recorded string
+	result, err := client.Generate(WithModelRecorder(context.Background(), &recorded), "hello")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if result.Model != "gpt-4o-mini" || recorded != "gpt-4o-mini" {
+		t.Errorf("result.Model = %q, recorded = %q, want gpt-4o-mini", result.Model, recorded)
+	}
+
+	var streamed string
+	stream := testutil.NewSSEServer(t, testutil.SSEConfig{Chunks: testutil.OpenAIDeltas("ok")})
+	streamClient := NewOpenAIClient("test-key", "cheap", WithBaseURL(stream.URL))
+	if err := streamClient.GenerateCodeStream(WithModelRecorder(context.Background(), &streamed), "hello", io.Discard); err != nil {
+		t.Fatalf("GenerateCodeStream failed: %v", err)
+	}
+	if streamed != "gpt-4o-mini" {
+		t.Errorf("stream recorded %q, want gpt-4o-mini", streamed)
+	}
+}
@@ -0,0 +1,110 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const moderationPath = "/moderations"
+
+type openAIModerationRequest struct {
+	Input string `json:"input"`
+}
+
+type openAIModerationResponse struct {
+	Results []struct {
+		Flagged    bool            `json:"flagged"`
+		Categories map[string]bool `json:"categories"`
+	} `json:"results"`
+	Error *openAIError `json:"error,omitempty"`
+}
+
+// WithModerationGuard screens every prompt with Moderate before generating,
+// failing flagged prompts with ErrContentFlagged. OpenAI clients only.
+func WithModerationGuard(enabled bool) Option {
+	return func(o *options) {
+		o.moderationGuard = enabled
+	}
+}
+
+// Moderate checks input against OpenAI's moderation endpoint and returns
+// whether it was flagged and the flagged categories, sorted.
+func (o *OpenAIClient) Moderate(ctx context.Context, input string) (flagged bool, categories []string, err error) {
+	ctx, end, err := o.life.begin(ctx)
+	if err != nil {
+		return false, nil, o.opts.providerError(err)
+	}
+	flagged, categories, err = o.moderation(ctx, input)
+	return flagged, categories, o.opts.providerError(end(err))
+}
+
+func (o *OpenAIClient) moderation(ctx context.Context, input string) (flagged bool, categories []string, err error) {
+	// Extra params are meant for chat requests and would be rejected here.
+	data, err := json.Marshal(openAIModerationRequest{Input: input})
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := o.opts.newRawJSONRequest(ctx, http.MethodPost, o.opts.endpointURL(moderationPath), data, o.authorize)
+	if err != nil {
+		return false, nil, err
+	}
+
+	resp, err := doJSONRequest(o.httpClient, req, o.opts.retryPolicy())
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to call moderation API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return false, nil, newAPIError(resp.StatusCode, body)
+	}
+
+	if err := checkContentType(resp, "application/json"); err != nil {
+		return false, nil, fmt.Errorf("%w: %s", err, bodySnippet(body))
+	}
+
+	var modResp openAIModerationResponse
+	if err := json.Unmarshal(body, &modResp); err != nil {
+		return false, nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if modResp.Error != nil {
+		return false, nil, &APIError{Type: modResp.Error.Type, Code: modResp.Error.Code, Message: modResp.Error.Message, Body: string(body)}
+	}
+
+	for _, r := range modResp.Results {
+		flagged = flagged || r.Flagged
+		for name, hit := range r.Categories {
+			if hit {
+				categories = append(categories, name)
+			}
+		}
+	}
+	sort.Strings(categories)
+	return flagged, categories, nil
+}
+
+// moderate enforces the moderation guard, if enabled.
+func (o *OpenAIClient) moderate(ctx context.Context, prompt string) error {
+	if !o.opts.moderationGuard {
+		return nil
+	}
+
+	flagged, categories, err := o.moderation(ctx, prompt)
+	if err != nil {
+		return fmt.Errorf("failed to moderate prompt: %w", err)
+	}
+	if flagged {
+		return fmt.Errorf("%w: %s", ErrContentFlagged, strings.Join(categories, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func moderationServer(t *testing.T, flagged bool, chatCalls *int) string {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/moderations", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["input"] == "" || len(req) != 1 {
+			t.Errorf("unexpected moderation request: %v", req)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if flagged {
+			w.Write([]byte(`{"results":[{"flagged":true,"categories":{"violence":true,"hate":true,"sexual":false}}]}`))
+			return
+		}
+		w.Write([]byte(`{"results":[{"flagged":false,"categories":{"violence":false}}]}`))
+	})
+	mux.HandleFunc("/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		*chatCalls++
+		writeChatResponse(w, "ok")
+	})
+	return newTestServer(t, mux.ServeHTTP).URL
+}
+
+func TestOpenAIClient_Moderate(t *testing.T) {
+	var chatCalls int
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(moderationServer(t, true, &chatCalls)))
+
+	flagged, categories, err := client.Moderate(context.Background(), "something nasty")
+	if err != nil {
+		t.Fatalf("Moderate failed: %v", err)
+	}
+	if !flagged || strings.Join(categories, ",") != "hate,violence" {
+		t.Errorf("flagged=%v categories=%q", flagged, categories)
+	}
+}
+
+func TestOpenAIClient_ModerationGuard(t *testing.T) {
+	t.Run("flagged", func(t *testing.T) {
+		var chatCalls int
+		client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(moderationServer(t, true, &chatCalls)), WithModerationGuard(true))
+
+		_, err := client.GenerateCode(context.Background(), "something nasty")
+		if !errors.Is(err, ErrContentFlagged) {
+			t.Fatalf("expected ErrContentFlagged, got %v", err)
+		}
+		if chatCalls != 0 {
+			t.Error("flagged prompt should not be sent for generation")
+		}
+	})
+
+	t.Run("clean", func(t *testing.T) {
+		var chatCalls int
+		client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(moderationServer(t, false, &chatCalls)), WithModerationGuard(true))
+
+		if _, err := client.GenerateCode(context.Background(), "a todo app"); err != nil {
+			t.Fatalf("GenerateCode failed: %v", err)
+		}
+		if chatCalls != 1 {
+			t.Errorf("expected 1 chat call, got %d", chatCalls)
+		}
+	})
+}
+
+func TestOpenAIClient_ModerationSkipsExtraParams(t *testing.T) {
+	var chatCalls int
+	client := NewOpenAIClient("test-key", "gpt-4",
+		WithBaseURL(moderationServer(t, false, &chatCalls)),
+		WithModerationGuard(true),
+		WithExtraParams(map[string]any{"top_k": 40}),
+	)
+
+	if _, err := client.GenerateCode(context.Background(), "a todo app"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+}
+
+func TestOpenAIClient_ModerationGuardSchema(t *testing.T) {
+	var chatCalls int
+	client := NewOpenAIClient("test-key", "gpt-4o", WithBaseURL(moderationServer(t, true, &chatCalls)), WithModerationGuard(true))
+
+	_, err := client.GenerateFilesSchema(context.Background(), "something nasty", json.RawMessage(`{"type":"object"}`))
+	if !errors.Is(err, ErrContentFlagged) {
+		t.Fatalf("expected ErrContentFlagged, got %v", err)
+	}
+	if chatCalls != 0 {
+		t.Error("flagged prompt should not be sent for generation")
+	}
+}
@@ -0,0 +1,25 @@
+package llm
+
+const defaultNIMBaseURL = "https://integrate.api.nvidia.com/v1"
+
+// NewNIMClient returns a client for NVIDIA NIM, as hosted on
+// build.nvidia.com or self-hosted, which serves the OpenAI chat completions
+// API with Bearer auth. Point WithBaseURL at a self-hosted deployment.
+func NewNIMClient(apiKey, model string, opts ...Option) *OpenAIClient {
+	if apiKey == "" {
+		panic("API Key must be provided")
+	}
+
+	if model == "" {
+		model = DefaultModel("nim")
+	}
+
+	o := defaultOptions()
+	o.baseURL = defaultNIMBaseURL
+	o.provider = "nim"
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return newOpenAIClient(apiKey, model, o)
+}
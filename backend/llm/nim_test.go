@@ -0,0 +1,33 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestNIMClient(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer nvapi-key" {
+			t.Errorf("unexpected auth header %q", r.Header.Get("Authorization"))
+		}
+		var req openAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "meta/llama-3.1-70b-instruct" {
+			t.Errorf("unexpected model %q", req.Model)
+		}
+		writeChatResponse(w, "ok")
+	})
+
+	client := NewNIMClient("nvapi-key", "", WithBaseURL(server.URL))
+	if client.opts.provider != "nim" {
+		t.Errorf("provider = %q", client.opts.provider)
+	}
+	if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+}
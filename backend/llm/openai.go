@@ -1,152 +1,408 @@
 package llm
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"time"
+
+	"github.com/egedolmaci/scaffolder/backend/parser"
 )
 
 type OpenAIClient struct {
 	httpClient *http.Client
 	apiKey     string
 	model      string
+	opts       options
+	life       *lifecycle
 }
 
 type openAIRequest struct {
-	Model    string          `json:"model"`
-	Messages []openAIMessage `json:"messages"`
+	Model               string          `json:"model"`
+	Messages            []openAIMessage `json:"messages"`
+	MaxTokens           int             `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int             `json:"max_completion_tokens,omitempty"`
+	Temperature         *float64        `json:"temperature,omitempty"`
+	ReasoningEffort     ReasoningEffort `json:"reasoning_effort,omitempty"`
+	Stream              bool            `json:"stream,omitempty"`
+
+	Store       bool              `json:"store,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	ServiceTier ServiceTier       `json:"service_tier,omitempty"`
+
+	StreamOptions  *openAIStreamOptions  `json:"stream_options,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+	Tools          []openAITool          `json:"tools,omitempty"`
 }
 
 type openAIMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role             string           `json:"role"`
+	Content          string           `json:"content"`
+	ReasoningContent string           `json:"reasoning_content,omitempty"`
+	Reasoning        string           `json:"reasoning,omitempty"`
+	Refusal          string           `json:"refusal,omitempty"`
+	ToolCalls        []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID       string           `json:"tool_call_id,omitempty"`
 }
 
 type openAIResponse struct {
-	Choices []openAIChoice `json:"choices"`
-	Error   *openAIError   `json:"error,omitempty"`
+	Choices     []openAIChoice `json:"choices"`
+	Usage       *openAIUsage   `json:"usage,omitempty"`
+	ServiceTier ServiceTier    `json:"service_tier,omitempty"`
+	Error       *openAIError   `json:"error,omitempty"`
+
+	// Set by gateways that report why a prompt was blocked.
+	PromptFeedback      json.RawMessage `json:"prompt_feedback,omitempty"`
+	PromptFilterResults json.RawMessage `json:"prompt_filter_results,omitempty"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
 }
 
 type openAIChoice struct {
-	Message openAIMessage `json:"message"`
+	Message              openAIMessage   `json:"message"`
+	FinishReason         string          `json:"finish_reason"`
+	ContentFilterResults json.RawMessage `json:"content_filter_results,omitempty"`
 }
 
 type openAIError struct {
 	Message string `json:"message"`
 	Type    string `json:"type"`
+	Code    string `json:"code"`
 }
 
-const systemPrompt = `You are a code generator that creates single-file web applications.
-
-Rules:
-- Generate complete, working HTML including inline CSS and JavaScript
-- Use modern JavaScript (ES6+)
-- Make it visually appealing with good CSS styling
-- Include all code in one HTML file
-- Be creative and functional
-- Do not include explanations, only code
-
-You MUST format your response with the code inside a markdown code block like this:
-
-` + "```html" + `
-<!DOCTYPE html>
-<html>
-<head>
-    <style>
-        /* CSS here */
-    </style>
-</head>
-<body>
-    <!-- HTML here -->
-    <script>
-        // JavaScript here
-    </script>
-</body>
-</html>
-` + "```" + `
-
-Important: Only return the code block, no additional text before or after.`
-
-func NewOpenAIClient(apiKey, model string) *OpenAIClient {
+// apiError converts an error sent in a successful response or stream.
+func (e *openAIError) apiError() *APIError {
+	return &APIError{Type: e.Type, Code: e.Code, Message: e.Message}
+}
+
+func NewOpenAIClient(apiKey, model string, opts ...Option) *OpenAIClient {
 	if apiKey == "" {
 		panic("API Key must be provided")
 	}
 
 	if model == "" {
-		model = "gpt-4"
+		model = DefaultModel("openai")
+	}
+
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
 	}
 
+	return newOpenAIClient(apiKey, model, o)
+}
+
+func newOpenAIClient(apiKey, model string, o options) *OpenAIClient {
 	return &OpenAIClient{
-		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
-		},
-		apiKey: apiKey,
-		model:  model,
+		httpClient: o.newHTTPClient(),
+		apiKey:     apiKey,
+		model:      model,
+		opts:       o,
+		life:       newLifecycle(),
 	}
 }
 
 func (o *OpenAIClient) GenerateCode(ctx context.Context, prompt string) (string, error) {
-	request := openAIRequest{
-		Model: o.model,
-		Messages: []openAIMessage{
-			{
-				Role:    "system",
-				Content: systemPrompt,
-			},
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
-	}
-
-	jsonData, err := json.Marshal(request)
+	result, err := o.Generate(ctx, prompt)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return "", err
 	}
+	return result.Code, nil
+}
 
-	bufferJson := bytes.NewReader(jsonData)
+// GenerateCodeRaw returns the unmodified model output alongside the code
+// extracted from its first fenced block. If extraction fails the raw text is
+// still returned together with the extraction error.
+func (o *OpenAIClient) GenerateCodeRaw(ctx context.Context, prompt string) (raw string, html string, err error) {
+	result, err := o.Generate(ctx, prompt)
+	if err != nil {
+		return "", "", err
+	}
+	raw = result.Raw
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bufferJson)
+	html, _, err = parser.ExtractCodeBlock(result.Code)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return raw, "", fmt.Errorf("failed to extract code: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	return raw, html, nil
+}
+
+// Generate returns the generated code along with any reasoning trace the
+// model produced, see GenerationResult.
+func (o *OpenAIClient) Generate(ctx context.Context, prompt string) (*GenerationResult, error) {
+	ctx, end, err := o.life.begin(ctx)
+	if err != nil {
+		return nil, o.opts.providerError(err)
+	}
+
+	start := o.opts.clock.Now()
+	o.opts.logRequest(ctx, o.model, prompt)
+
+	callCtx, cancel := o.opts.callContext(ctx)
+	defer cancel()
+
+	result, err := o.opts.generate(ctx, func() (*GenerationResult, error) {
+		return o.send(callCtx, prompt)
+	})
+	err = o.opts.providerError(end(err))
 
-	resp, err := o.httpClient.Do(req)
+	var content string
+	if result != nil {
+		content = result.Code
+	}
+	o.opts.logResponse(ctx, o.model, content, o.opts.clock.Now().Sub(start), err)
+
+	return result, err
+}
+
+func (o *OpenAIClient) send(ctx context.Context, prompt string) (*GenerationResult, error) {
+	request, err := o.buildRequest(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.moderate(ctx, prompt); err != nil {
+		return nil, err
+	}
+
+	var toolUsage Usage
+	for round := 0; ; round++ {
+		body, meta, err := o.postFallback(ctx, &request)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := parseOpenAIResponse(body)
+		var toolErr *ToolCallError
+		if len(o.opts.toolHandlers) == 0 || !errors.As(err, &toolErr) {
+			if err != nil {
+				return nil, err
+			}
+			result.Usage.PromptTokens += toolUsage.PromptTokens
+			result.Usage.CompletionTokens += toolUsage.CompletionTokens
+			meta.Model = responseModel(body)
+			result.Meta = meta
+			return o.finishResult(ctx, result, request)
+		}
+
+		if round >= o.opts.maxToolRounds {
+			return nil, fmt.Errorf("%w after %d rounds", ErrToolLoopLimit, round)
+		}
+		toolUsage.PromptTokens += toolErr.Usage.PromptTokens
+		toolUsage.CompletionTokens += toolErr.Usage.CompletionTokens
+
+		messages, err := o.opts.runTools(ctx, toolErr.Calls)
+		if err != nil {
+			return nil, err
+		}
+		request.Messages = append(request.Messages, messages...)
+	}
+}
+
+func (o *OpenAIClient) finishResult(ctx context.Context, result *GenerationResult, request openAIRequest) (*GenerationResult, error) {
+	result.Model = request.Model
+	if err := o.opts.checkOutput(ctx, result); err != nil {
+		return nil, err
+	}
+	if o.opts.captureSentMessages {
+		result.SentMessages = fromOpenAIMessages(request.Messages)
+	}
+	return result, nil
+}
+
+// post sends a non-streaming request and returns the JSON response body
+// and the response's metadata.
+func (o *OpenAIClient) post(ctx context.Context, request openAIRequest) ([]byte, *ResponseMeta, error) {
+	req, err := o.newRequest(ctx, request)
+	if err != nil {
+		return nil, nil, err
+	}
+	var etagKey string
+	if o.opts.etags != nil {
+		etagKey = o.opts.etags.prepare(req)
+	}
+
+	resp, err := doJSONRequest(o.httpClient, req, o.opts.retryPolicy())
 
 	if err != nil {
-		return "", fmt.Errorf("failed to call OpenAI API: %w", err)
+		return nil, nil, fmt.Errorf("failed to call API: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified && o.opts.etags != nil {
+		if body, ok := o.opts.etags.lookup(etagKey); ok {
+			return body, newResponseMeta(resp), nil
+		}
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("OpenAI API error (status %d) %s", resp.StatusCode, string(body))
+		if err := filteredPromptError(body); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, newAPIError(resp.StatusCode, body)
 	}
 
+	if err := checkContentType(resp, "application/json"); err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", err, bodySnippet(body))
+	}
+
+	if o.opts.etags != nil {
+		o.opts.etags.store(etagKey, resp, body)
+	}
+	return body, newResponseMeta(resp), nil
+}
+
+// responseModel returns the model named in a response body.
+func responseModel(body []byte) string {
+	var resp struct {
+		Model string `json:"model"`
+	}
+	json.Unmarshal(body, &resp)
+	return resp.Model
+}
+
+func parseOpenAIResponse(body []byte) (*GenerationResult, error) {
 	var openAIResp openAIResponse
 	if err := json.Unmarshal(body, &openAIResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	if openAIResp.Error != nil {
-		return "", fmt.Errorf("OpenAI API error: %s", openAIResp.Error.Message)
+		return nil, openAIResp.Error.apiError()
 	}
 
 	if len(openAIResp.Choices) == 0 {
-		return "", fmt.Errorf("no response from OpenAI")
+		if len(openAIResp.PromptFeedback) > 0 {
+			return nil, contentFilteredError(openAIResp.PromptFeedback)
+		}
+		return nil, errors.New("empty response")
+	}
+
+	choice := openAIResp.Choices[0]
+	if choice.FinishReason == "content_filter" {
+		detail := choice.ContentFilterResults
+		if len(detail) == 0 {
+			detail = openAIResp.PromptFilterResults
+		}
+		return nil, contentFilteredError(detail)
+	}
+	if choice.Message.Refusal != "" {
+		return nil, fmt.Errorf("%w: %s", ErrModelRefused, choice.Message.Refusal)
+	}
+	if len(choice.Message.ToolCalls) > 0 {
+		toolErr := &ToolCallError{Calls: toToolCalls(choice.Message.ToolCalls)}
+		if u := openAIResp.Usage; u != nil {
+			toolErr.Usage = Usage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens}
+		}
+		return nil, toolErr
+	}
+
+	result := parseOpenAIMessage(choice.Message)
+	result.ServiceTier = openAIResp.ServiceTier
+	if u := openAIResp.Usage; u != nil {
+		result.Usage = Usage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens}
+	}
+
+	return result, nil
+}
+
+func (o *OpenAIClient) buildRequest(ctx context.Context, prompt string) (openAIRequest, error) {
+	messages, err := o.opts.buildMessages(ctx, prompt)
+	if err != nil {
+		return openAIRequest{}, err
+	}
+	model := o.opts.routeModel(o.model, prompt)
+	recordModel(ctx, model)
+	return o.buildChatRequest(model, messages)
+}
+
+func (o *OpenAIClient) buildChatRequest(model string, messages []Message) (openAIRequest, error) {
+	if err := o.opts.checkPromptSize(model, messages); err != nil {
+		return openAIRequest{}, err
+	}
+	if err := o.opts.checkServiceTier(); err != nil {
+		return openAIRequest{}, err
+	}
+
+	request := openAIRequest{
+		Model:       model,
+		Messages:    toOpenAIMessages(messages),
+		Tools:       o.opts.openAITools(),
+		Store:       o.opts.store,
+		Metadata:    o.opts.metadata,
+		ServiceTier: o.opts.serviceTier,
+	}
+
+	// Reasoning models reject temperature and max_tokens.
+	if o.opts.isReasoningModel(model) {
+		request.MaxCompletionTokens = o.opts.maxTokens
+		request.ReasoningEffort = o.opts.reasoningEffort
+	} else {
+		request.MaxTokens = o.opts.maxTokens
+		request.Temperature = o.opts.temperature
+	}
+
+	return request, nil
+}
+
+func (o *OpenAIClient) newRequest(ctx context.Context, request openAIRequest) (*http.Request, error) {
+	return o.opts.newJSONRequest(ctx, o.opts.chatMethod, o.opts.endpointURL(o.opts.chatPath), request, o.authorize)
+}
+
+func (o *OpenAIClient) authorize(req *http.Request) error {
+	if o.opts.tokenProvider != nil {
+		token, err := o.opts.tokenProvider.Token(req.Context())
+		if err != nil {
+			return fmt.Errorf("failed to get auth token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+
+	if o.opts.apiKeyHeader {
+		req.Header.Set("api-key", o.apiKey)
+		return nil
 	}
 
-	return openAIResp.Choices[0].Message.Content, nil
+	req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	return nil
+}
+
+func parseOpenAIMessage(m openAIMessage) *GenerationResult {
+	reasoning := m.ReasoningContent
+	if reasoning == "" {
+		reasoning = m.Reasoning
+	}
+	if reasoning != "" {
+		return &GenerationResult{Code: m.Content, Reasoning: reasoning, Raw: m.Content}
+	}
+
+	reasoning, code := splitReasoning(m.Content)
+	return &GenerationResult{Code: code, Reasoning: reasoning, Raw: m.Content}
+}
+
+func fromOpenAIMessages(messages []openAIMessage) []Message {
+	out := make([]Message, len(messages))
+	for i, m := range messages {
+		out[i] = Message{Role: Role(m.Role), Content: m.Content}
+	}
+	return out
+}
+
+func toOpenAIMessages(messages []Message) []openAIMessage {
+	out := make([]openAIMessage, len(messages))
+	for i, m := range messages {
+		out[i] = openAIMessage{Role: string(m.Role), Content: m.Content}
+	}
+	return out
 }
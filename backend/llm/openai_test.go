@@ -1,10 +1,20 @@
 package llm
 
 import (
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/egedolmaci/scaffolder/backend/parser"
 )
 
 // TestOpenAIClient_GenerateCode tests the OpenAI client
@@ -35,9 +45,297 @@ func TestOpenAIClient_GenerateCode(t *testing.T) {
 	t.Logf("Result preview: %s...", result[:min(200, len(result))])
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
+func newTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func chatResponseJSON(content string) []byte {
+	data, _ := json.Marshal(openAIResponse{
+		Choices: []openAIChoice{{Message: openAIMessage{Role: "assistant", Content: content}}},
+	})
+	return data
+}
+
+func writeChatResponse(w http.ResponseWriter, content string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(chatResponseJSON(content))
+}
+
+func TestOpenAIClient_GzipResponse(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			t.Errorf("expected Accept-Encoding gzip, got %q", r.Header.Get("Accept-Encoding"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		zw.Write(chatResponseJSON("<html></html>"))
+		zw.Close()
+	})
+
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	result, err := client.GenerateCode(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	if result != "<html></html>" {
+		t.Fatalf("unexpected result: %q", result)
+	}
+}
+
+func TestOpenAIClient_CompressedRequestDecompresses(t *testing.T) {
+	prompt := strings.Repeat("a large context file ", 200)
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if enc := r.Header.Get("Content-Encoding"); enc != "gzip" {
+			t.Errorf("expected Content-Encoding gzip, got %q", enc)
+		}
+
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("body is not gzip: %v", err)
+			return
+		}
+		var req openAIRequest
+		if err := json.NewDecoder(zr).Decode(&req); err != nil {
+			t.Errorf("failed to decode decompressed body: %v", err)
+			return
+		}
+		if got := req.Messages[len(req.Messages)-1].Content; got != prompt {
+			t.Errorf("prompt did not survive compression")
+		}
+		writeChatResponse(w, "ok")
+	})
+
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithRequestCompression(true))
+
+	if _, err := client.GenerateCode(context.Background(), prompt); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+}
+
+func TestOpenAIClient_CompressionFallsBackOn415(t *testing.T) {
+	var encodings []string
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		enc := r.Header.Get("Content-Encoding")
+		encodings = append(encodings, enc)
+		if enc == "gzip" {
+			io.Copy(io.Discard, r.Body)
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+
+		var req openAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("fallback body is not plain JSON: %v", err)
+		}
+		writeChatResponse(w, "ok")
+	})
+
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithRequestCompression(true))
+
+	if _, err := client.GenerateCode(context.Background(), strings.Repeat("x", 2048)); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	if len(encodings) != 2 || encodings[0] != "gzip" || encodings[1] != "" {
+		t.Errorf("unexpected encodings sent: %q", encodings)
+	}
+}
+
+func TestOpenAIClient_SmallRequestsNotCompressed(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if enc := r.Header.Get("Content-Encoding"); enc != "" {
+			t.Errorf("expected no Content-Encoding for small body, got %q", enc)
+		}
+		writeChatResponse(w, "ok")
+	})
+
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithRequestCompression(true))
+
+	if _, err := client.GenerateCode(context.Background(), "hi"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+}
+
+func TestOpenAIClient_GenerateCodeRaw(t *testing.T) {
+	raw := "Here you go:\n```html\n<!DOCTYPE html>\n<html></html>\n```"
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeChatResponse(w, raw)
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	gotRaw, html, err := client.GenerateCodeRaw(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("GenerateCodeRaw failed: %v", err)
+	}
+	if gotRaw != raw {
+		t.Errorf("raw = %q, want %q", gotRaw, raw)
+	}
+	if html != "<!DOCTYPE html>\n<html></html>" {
+		t.Errorf("html = %q", html)
+	}
+}
+
+func TestOpenAIClient_GenerateCodeRawKeepsThink(t *testing.T) {
+	raw := "<think>plan the page</think>\n```html\n<p>hi</p>\n```"
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeChatResponse(w, raw)
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	gotRaw, html, err := client.GenerateCodeRaw(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("GenerateCodeRaw failed: %v", err)
+	}
+	if gotRaw != raw || html != "<p>hi</p>" {
+		t.Errorf("raw=%q html=%q", gotRaw, html)
+	}
+}
+
+func TestOpenAIClient_GenerateCodeRawKeepsRawOnExtractionFailure(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeChatResponse(w, "sorry, no code today")
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	raw, html, err := client.GenerateCodeRaw(context.Background(), "hello")
+	if !errors.Is(err, parser.ErrNoCodeBlock) {
+		t.Fatalf("expected ErrNoCodeBlock, got %v", err)
+	}
+	if raw != "sorry, no code today" || html != "" {
+		t.Errorf("unexpected raw=%q html=%q", raw, html)
 	}
-	return b
-}
\ No newline at end of file
+}
+
+func TestOpenAIClient_DisableSystemPrompt(t *testing.T) {
+	for _, disable := range []bool{false, true} {
+		server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			var req openAIRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("failed to decode request: %v", err)
+				return
+			}
+
+			wantMessages := 2
+			if disable {
+				wantMessages = 1
+			}
+			if len(req.Messages) != wantMessages {
+				t.Errorf("disable=%v: got %d messages, want %d", disable, len(req.Messages), wantMessages)
+			}
+			if last := req.Messages[len(req.Messages)-1]; last.Role != "user" {
+				t.Errorf("disable=%v: last message role = %q", disable, last.Role)
+			}
+			writeChatResponse(w, "ok")
+		})
+
+		opts := []Option{WithBaseURL(server.URL)}
+		if disable {
+			opts = append(opts, WithDisableSystemPrompt())
+		}
+		client := NewOpenAIClient("test-key", "gpt-4", opts...)
+
+		if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+			t.Fatalf("GenerateCode failed: %v", err)
+		}
+	}
+}
+
+func TestOpenAIClient_GenerateCodeCancellation(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices the client going away once the body is read.
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+			writeChatResponse(w, "too late")
+		}
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		start := time.Now()
+		_, err := client.GenerateCode(ctx, "hello")
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("GenerateCode took %v to return after cancel", elapsed)
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := client.GenerateCode(ctx, "hello")
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("GenerateCode took %v to return after deadline", elapsed)
+		}
+	})
+}
+
+func BenchmarkParseResponse(b *testing.B) {
+	var page strings.Builder
+	page.WriteString("```html\n<!DOCTYPE html>\n<html><body>\n")
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&page, "<div class=\"row\" data-i=\"%d\">Row \"%d\" &amp; more</div>\n", i, i)
+	}
+	page.WriteString("</body></html>\n```")
+	body := chatResponseJSON(page.String())
+
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if _, err := parseOpenAIResponse(body); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestOpenAIClient_ChatPathAndMethod(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/proxy/llm/chat" || r.URL.Query().Get("tenant") != "acme" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		writeChatResponse(w, "ok")
+	})
+
+	client := NewOpenAIClient("test-key", "gpt-4",
+		WithBaseURL(server.URL),
+		WithChatPath("/proxy/llm/chat?tenant=acme"),
+		WithChatMethod(http.MethodPut),
+	)
+
+	if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+}
+
+func TestOpenAIClient_ChatMethodWithoutBody(t *testing.T) {
+	var calls int
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		writeChatResponse(w, "ok")
+	})
+
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithChatMethod(http.MethodGet))
+	if _, err := client.GenerateCode(context.Background(), "hello"); err == nil || !strings.Contains(err.Error(), `chat method "GET"`) {
+		t.Fatalf("expected chat method error, got %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("request should not be sent, got %d calls", calls)
+	}
+}
@@ -0,0 +1,300 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultChatPath      = "/chat/completions"
+	defaultTimeout       = 60 * time.Second
+)
+
+// gzipMinBytes is the smallest request body worth compressing.
+const gzipMinBytes = 1024
+
+type Option func(*options)
+
+type options struct {
+	baseURL            string
+	provider           string
+	chatPath           string
+	chatMethod         string
+	apiVersion         string
+	timeout            time.Duration
+	clock              Clock
+	proxyURL           *url.URL
+	proxyErr           error
+	chatMethodErr      error
+	insecureSkipVerify bool
+	traceWriter        io.Writer
+	compressRequests   bool
+	etags              *etagCache
+	maxConcurrent      int
+	retry              retryPolicy
+	streamUsage        func(Usage)
+	streamReconnects   int
+
+	apiKeyHeader  bool
+	tokenProvider TokenProvider
+
+	maxTokens   int
+	temperature *float64
+	// defaultMaxTokens is the max_tokens a provider sends without
+	// WithMaxTokens, zero if it sends none.
+	defaultMaxTokens int
+
+	modelRouter     func(prompt string) string
+	fallbackModel   string
+	reasoningModel  *bool
+	reasoningEffort ReasoningEffort
+
+	extraParams map[string]any
+	store       bool
+	serviceTier ServiceTier
+	metadata    map[string]string
+
+	logger       *slog.Logger
+	redactor     func(string) string
+	dumpRequests bool
+
+	systemPrompt        string
+	systemPromptFunc    func(ctx context.Context) string
+	disableSystemPrompt bool
+	qualityProfile      QualityProfile
+	preProcessors       []PromptPreProcessor
+	cachePrefix         bool
+	thinkingBudget      int
+	returnThinking      bool
+	formatHTML          bool
+	sanitizePrompt      bool
+	currentTime         bool
+	captureSentMessages bool
+	moderationGuard     bool
+	tools               []Tool
+	toolHandlers        map[string]ToolHandler
+	maxToolRounds       int
+	externalResources   ExternalResourcePolicy
+	headlessValidator   HeadlessValidator
+	validators          []ResponseValidator
+	maxPromptTokens     int
+	allowEmptyPrompt    bool
+}
+
+func defaultOptions() options {
+	return options{
+		baseURL:    defaultOpenAIBaseURL,
+		provider:   "openai",
+		chatPath:   defaultChatPath,
+		chatMethod: http.MethodPost,
+		timeout:    defaultTimeout,
+		clock:      realClock{},
+		retry: retryPolicy{
+			baseDelay: defaultRetryBaseDelay,
+			maxDelay:  defaultRetryMaxDelay,
+			jitter:    JitterFull,
+			clock:     realClock{},
+		},
+		redactor:      DefaultRedactor,
+		maxToolRounds: defaultMaxToolRounds,
+	}
+}
+
+func (o *options) endpointURL(path string) string {
+	u := o.baseURL + path
+	if o.apiVersion != "" {
+		sep := "?"
+		if strings.Contains(u, "?") {
+			sep = "&"
+		}
+		u += sep + url.Values{"api-version": {o.apiVersion}}.Encode()
+	}
+	return u
+}
+
+// retryPolicy returns the retry policy for the client's requests.
+func (o *options) retryPolicy() retryPolicy {
+	p := o.retry
+	p.baseURL = o.baseURL
+	return p
+}
+
+// WithBaseURL points the client at an OpenAI-compatible endpoint
+// (e.g. a self-hosted gateway). The URL should include the version prefix.
+func WithBaseURL(baseURL string) Option {
+	return func(o *options) {
+		o.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithTimeout sets how long a call may take, retries and stream reads
+// included. Defaults to 60s, zero disables it. It is applied through the
+// call's context, so WithPerCallTimeout can override it per call.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithProxy sends requests through the HTTP(S) proxy at proxyURL instead
+// of the one from the environment. An invalid URL fails every request.
+func WithProxy(proxyURL string) Option {
+	return func(o *options) {
+		u, err := url.Parse(proxyURL)
+		if err == nil && (u.Scheme == "" || u.Host == "") {
+			err = errors.New("missing scheme or host")
+		}
+		if err != nil {
+			o.proxyErr = fmt.Errorf("invalid proxy URL %q: %w", proxyURL, err)
+			return
+		}
+		o.proxyURL, o.proxyErr = u, nil
+	}
+}
+
+// WithInsecureSkipTLSVerify disables TLS certificate verification, so any
+// server, including an attacker's, is trusted. For development against a
+// local gateway with a self-signed certificate only; never use it in
+// production.
+func WithInsecureSkipTLSVerify(skip bool) Option {
+	return func(o *options) {
+		o.insecureSkipVerify = skip
+	}
+}
+
+// WithMaxTokens caps the number of tokens the model may generate.
+func WithMaxTokens(n int) Option {
+	return func(o *options) {
+		o.maxTokens = n
+	}
+}
+
+func WithTemperature(t float64) Option {
+	return func(o *options) {
+		o.temperature = &t
+	}
+}
+
+// WithChatPath replaces the chat endpoint path appended to the base URL
+// (default "/chat/completions", "/messages" for Anthropic). It may carry a
+// query string for gateways that route on query parameters.
+func WithChatPath(path string) Option {
+	return func(o *options) {
+		o.chatPath = path
+	}
+}
+
+// WithChatMethod changes the HTTP method used for the chat endpoint
+// (default POST). Only methods that carry a body, POST, PUT and PATCH, are
+// accepted since the request is sent as JSON; any other fails every call.
+func WithChatMethod(method string) Option {
+	return func(o *options) {
+		switch method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			o.chatMethod, o.chatMethodErr = method, nil
+		default:
+			o.chatMethodErr = fmt.Errorf("chat method %q does not carry a request body", method)
+		}
+	}
+}
+
+// WithRequestCompression gzips request bodies larger than 1KB and sets
+// Content-Encoding: gzip. Off by default since api.openai.com doesn't
+// accept compressed requests. If the server answers 415 the request is
+// resent uncompressed. Response decompression is always handled by the
+// HTTP transport.
+func WithRequestCompression(enabled bool) Option {
+	return func(o *options) {
+		o.compressRequests = enabled
+	}
+}
+
+// WithRetries retries requests that fail with a network error, 429 or 5xx
+// up to n additional times.
+//
+// Streams are retried only while that is invisible to the caller: when the
+// request fails, or the connection drops before the first delta, the
+// stream is resent as is. Once output has been written it can't be taken
+// back, so a drop mid-stream returns the error with the partial output
+// left in the writer, see GenerateCodeStreamPartial. The stream is never
+// restarted from scratch, which would repeat text; WithStreamReconnect
+// opts in to continuing it instead.
+func WithRetries(n int) Option {
+	return func(o *options) {
+		o.retry.maxRetries = n
+	}
+}
+
+// WithBackoff sets the exponential backoff base delay and its ceiling.
+func WithBackoff(base, max time.Duration) Option {
+	return func(o *options) {
+		o.retry.baseDelay = base
+		o.retry.maxDelay = max
+	}
+}
+
+// WithRetryIf replaces the default retry decision (network errors, 429 and
+// 5xx). resp is nil when err is set. Error response bodies may be read by
+// fn; they are buffered and remain readable afterwards. Retries are still
+// capped by WithRetries.
+func WithRetryIf(fn func(resp *http.Response, err error) bool) Option {
+	return func(o *options) {
+		o.retry.retryIf = fn
+	}
+}
+
+// WithJitter sets how backoff delays are randomized. Defaults to JitterFull.
+func WithJitter(strategy JitterStrategy) Option {
+	return func(o *options) {
+		o.retry.jitter = strategy
+	}
+}
+
+// WithDisableSystemPrompt sends only the user message. Useful for
+// fine-tuned models that already know the output format.
+func WithDisableSystemPrompt() Option {
+	return func(o *options) {
+		o.disableSystemPrompt = true
+	}
+}
+
+// WithMaxPromptTokens rejects prompts whose estimated size exceeds n tokens
+// with ErrPromptTooLarge before anything is sent. Prompts that don't fit the
+// model's known context window are rejected regardless.
+func WithMaxPromptTokens(n int) Option {
+	return func(o *options) {
+		o.maxPromptTokens = n
+	}
+}
+
+// WithTokenProvider authenticates with "Authorization: Bearer <token>",
+// fetching the token from tp on every request. On Azure this replaces the
+// api-key header with Entra ID (AAD) auth.
+func WithTokenProvider(tp TokenProvider) Option {
+	return func(o *options) {
+		o.tokenProvider = tp
+	}
+}
+
+// WithStreamReconnect resends a stream up to n times when the connection
+// drops before it completes. The text received so far is sent back as an
+// assistant message followed by a request to continue, so output resumes
+// where it stopped. Reconnecting stops once the partial output no longer
+// fits the model's context window.
+//
+// Each reconnect is a new request: the prompt and partial output are billed
+// again as input tokens, and because the backend isn't truly resuming, the
+// continuation can occasionally repeat or diverge from the partial text.
+func WithStreamReconnect(n int) Option {
+	return func(o *options) {
+		o.streamReconnects = n
+	}
+}
@@ -0,0 +1,118 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/egedolmaci/scaffolder/backend/parser"
+)
+
+// ExternalResourcePolicy decides what happens to generated code that loads
+// resources from another origin.
+type ExternalResourcePolicy int
+
+const (
+	// ExternalResourcesAllow leaves output untouched (the default).
+	ExternalResourcesAllow ExternalResourcePolicy = iota
+	// ExternalResourcesReject fails the call with ErrExternalResource. It
+	// runs the NoExternalResources validator, so WithRetries regenerates.
+	ExternalResourcesReject
+	// ExternalResourcesStrip removes the offending elements and @imports.
+	ExternalResourcesStrip
+)
+
+// WithExternalResources enforces policy on generated code, for deployments
+// that need fully self-contained pages. External means an absolute http(s)
+// or protocol-relative URL in a resource attribute (<script src>,
+// <link href>, <img src>...) or a CSS @import. Streaming output, which is
+// already written when it could be checked, is not covered.
+func WithExternalResources(policy ExternalResourcePolicy) Option {
+	return func(o *options) {
+		o.externalResources = policy
+	}
+}
+
+// WithFormatHTML re-indents generated HTML documents with
+// parser.FormatHTML for readability. Output without an <html> element or
+// doctype, such as a fragment or another language, is left as is, as is
+// the text around a fenced block. It is off by default, which keeps
+// minified output minified. Streaming output is not covered.
+func WithFormatHTML(enabled bool) Option {
+	return func(o *options) {
+		o.formatHTML = enabled
+	}
+}
+
+// formatHTML applies WithFormatHTML to the document in code.
+func formatHTML(code string) string {
+	doc, lang, err := parser.ExtractCodeBlock(code)
+	if err != nil {
+		doc = code
+	} else if lang != "" && !strings.EqualFold(lang, "html") {
+		return code
+	}
+
+	lower := strings.ToLower(doc)
+	if !strings.Contains(lower, "<html") && !strings.Contains(lower, "<!doctype") {
+		return code
+	}
+	formatted, err := parser.FormatHTML(doc)
+	if err != nil || !strings.Contains(code, doc) {
+		return code
+	}
+	return strings.Replace(code, doc, strings.TrimSuffix(formatted, "\n"), 1)
+}
+
+// HeadlessValidator loads a generated HTML document in a browser and
+// reports errors it raises. Package headless provides one backed by
+// chromedp.
+type HeadlessValidator interface {
+	Validate(ctx context.Context, html string) error
+}
+
+// WithHeadlessValidation fails generations whose page v reports errors
+// for, e.g. uncaught exceptions on load. The failure wraps
+// ErrHeadlessValidation.
+func WithHeadlessValidation(v HeadlessValidator) Option {
+	return func(o *options) {
+		o.headlessValidator = v
+	}
+}
+
+// extractedCode returns the code block in code, or code itself if it
+// has none.
+func extractedCode(code string) string {
+	doc, _, err := parser.ExtractCodeBlock(code)
+	if err != nil {
+		return code
+	}
+	return doc
+}
+
+// checkOutput applies the output policies to a finished generation.
+func (o *options) checkOutput(ctx context.Context, result *GenerationResult) error {
+	if o.externalResources == ExternalResourcesStrip {
+		result.Code, _ = parser.StripExternalResources(result.Code)
+	}
+	if o.formatHTML {
+		result.Code = formatHTML(result.Code)
+	}
+
+	code, lang, err := parser.ExtractCodeBlock(result.Code)
+	if err != nil {
+		code, lang = result.Code, ""
+	}
+	for _, validate := range o.responseValidators() {
+		if err := validate(code, lang); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+		}
+	}
+
+	if o.headlessValidator != nil {
+		if err := o.headlessValidator.Validate(ctx, code); err != nil {
+			return fmt.Errorf("%w: %w", ErrHeadlessValidation, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const cdnPage = "```html\n<html><head><script src=\"https://cdn.tailwindcss.com\"></script></head><body>hi</body></html>\n```"
+
+func TestWithExternalResources(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeChatResponse(w, cdnPage)
+	})
+
+	t.Run("allow", func(t *testing.T) {
+		client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+		got, err := client.GenerateCode(context.Background(), "hello")
+		if err != nil || got != cdnPage {
+			t.Fatalf("expected untouched output, got %q, %v", got, err)
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithExternalResources(ExternalResourcesReject))
+		_, err := client.GenerateCode(context.Background(), "hello")
+		if !errors.Is(err, ErrExternalResource) {
+			t.Fatalf("expected ErrExternalResource, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "https://cdn.tailwindcss.com") {
+			t.Errorf("error should list the URL: %v", err)
+		}
+	})
+
+	t.Run("strip", func(t *testing.T) {
+		client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithExternalResources(ExternalResourcesStrip))
+		got, err := client.GenerateCode(context.Background(), "hello")
+		if err != nil {
+			t.Fatalf("GenerateCode failed: %v", err)
+		}
+		if want := "```html\n<html><head></head><body>hi</body></html>\n```"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+}
+
+type fakeHeadlessValidator struct {
+	got string
+	err error
+}
+
+func (f *fakeHeadlessValidator) Validate(ctx context.Context, html string) error {
+	f.got = html
+	return f.err
+}
+
+func TestWithHeadlessValidation(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeChatResponse(w, "```html\n<script>boom()</script>\n```")
+	})
+
+	ok := &fakeHeadlessValidator{}
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithHeadlessValidation(ok))
+	if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	if ok.got != "<script>boom()</script>" {
+		t.Errorf("validator got %q, want the extracted document", ok.got)
+	}
+
+	failing := &fakeHeadlessValidator{err: errors.New("ReferenceError: boom is not defined")}
+	client = NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithHeadlessValidation(failing))
+	_, err := client.GenerateCode(context.Background(), "hello")
+	if !errors.Is(err, ErrHeadlessValidation) || !errors.Is(err, failing.err) {
+		t.Fatalf("expected ErrHeadlessValidation wrapping the validator error, got %v", err)
+	}
+}
+
+func TestWithFormatHTML(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeChatResponse(w, "Here you go:\n```html\n<html><head></head><body><div><p>hi</p></div></body></html>\n```")
+	})
+
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithFormatHTML(true))
+	got, err := client.GenerateCode(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	want := "Here you go:\n```html\n<html>\n  <head></head>\n  <body>\n    <div>\n      <p>hi</p>\n    </div>\n  </body>\n</html>\n```"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if code := formatHTML("```css\nbody { color: red }\n```"); code != "```css\nbody { color: red }\n```" {
+		t.Errorf("non-HTML output changed: %q", code)
+	}
+}
@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// reservedParams can't be set through WithExtraParams because the client
+// controls them.
+var reservedParams = map[string]bool{
+	"model":    true,
+	"messages": true,
+	"stream":   true,
+}
+
+// WithExtraParams merges arbitrary fields into the request JSON, for
+// provider knobs without a dedicated option (logit_bias, vendor fields...).
+// Fields set by explicit options take precedence over extras. Reserved
+// fields (model, messages, stream) are rejected when the request is built.
+func WithExtraParams(params map[string]any) Option {
+	return func(o *options) {
+		if o.extraParams == nil {
+			o.extraParams = make(map[string]any, len(params))
+		}
+		for k, v := range params {
+			o.extraParams[k] = v
+		}
+	}
+}
+
+func (o *options) marshalBody(request any) ([]byte, error) {
+	data, err := json.Marshal(request)
+	if err != nil || len(o.extraParams) == 0 {
+		return data, err
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, err
+	}
+
+	for k, v := range o.extraParams {
+		if reservedParams[k] {
+			return nil, fmt.Errorf("extra param %q is reserved", k)
+		}
+		if _, ok := body[k]; !ok {
+			body[k] = v
+		}
+	}
+
+	return json.Marshal(body)
+}
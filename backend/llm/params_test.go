@@ -0,0 +1,54 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIClient_ExtraParams(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			return
+		}
+
+		if body["parallel_tool_calls"] != false {
+			t.Errorf("extra param missing: %v", body)
+		}
+		if body["max_tokens"] != float64(100) {
+			t.Errorf("explicit option should win over extra param, got %v", body["max_tokens"])
+		}
+		if body["model"] != "gpt-4" {
+			t.Errorf("model changed: %v", body["model"])
+		}
+		writeChatResponse(w, "ok")
+	})
+
+	client := NewOpenAIClient("test-key", "gpt-4",
+		WithBaseURL(server.URL),
+		WithMaxTokens(100),
+		WithExtraParams(map[string]any{
+			"parallel_tool_calls": false,
+			"max_tokens":          9999,
+		}),
+	)
+
+	if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+}
+
+func TestOpenAIClient_ExtraParamsReserved(t *testing.T) {
+	for _, key := range []string{"model", "messages", "stream"} {
+		client := NewOpenAIClient("test-key", "gpt-4", WithExtraParams(map[string]any{key: "x"}))
+
+		_, err := client.GenerateCode(context.Background(), "hello")
+		if err == nil || !strings.Contains(err.Error(), "reserved") {
+			t.Errorf("%s: expected reserved param error, got %v", key, err)
+		}
+	}
+}
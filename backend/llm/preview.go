@@ -0,0 +1,60 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/egedolmaci/scaffolder/backend/parser"
+)
+
+// previewCheckBytes is how much new output accumulates between parse
+// attempts, so long streams aren't re-tokenized on every delta.
+const previewCheckBytes = 256
+
+// GenerateCodeStreamPreview streams like GenerateCodeStream and calls
+// onPreview once, with the code received so far, as soon as it contains a
+// complete <body>. The markdown fence is stripped before parsing.
+func (o *OpenAIClient) GenerateCodeStreamPreview(ctx context.Context, prompt string, w io.Writer, onPreview func(html string)) error {
+	pw := &previewWriter{w: w, onPreview: onPreview}
+	if err := o.GenerateCodeStream(ctx, prompt, pw); err != nil {
+		return err
+	}
+
+	pw.check()
+	return nil
+}
+
+type previewWriter struct {
+	w         io.Writer
+	onPreview func(html string)
+
+	buf       strings.Builder
+	checkedAt int
+	ready     bool
+}
+
+func (p *previewWriter) Write(b []byte) (int, error) {
+	p.buf.Write(b)
+	if p.buf.Len()-p.checkedAt >= previewCheckBytes || strings.Contains(string(b), "</body") {
+		p.check()
+	}
+	return p.w.Write(b)
+}
+
+func (p *previewWriter) check() {
+	if p.ready {
+		return
+	}
+	p.checkedAt = p.buf.Len()
+
+	doc := p.buf.String()
+	if code, _, err := parser.ExtractCodeBlock(doc); err == nil {
+		doc = code
+	}
+
+	if parser.HasCompleteBody(doc) {
+		p.ready = true
+		p.onPreview(doc)
+	}
+}
@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// ErrPromptVariables is wrapped by the error PromptSpec.Render returns for
+// missing, unknown or mistyped variables.
+var ErrPromptVariables = errors.New("invalid prompt variables")
+
+// PromptVar declares a variable of a PromptSpec. Kind is the kind its value
+// must have, e.g. reflect.String or reflect.Int; reflect.Invalid accepts
+// any value. Optional variables may be left out, and render as an empty
+// string rather than "<no value>".
+type PromptVar struct {
+	Name     string
+	Kind     reflect.Kind
+	Optional bool
+}
+
+// PromptSpec is a prompt template with declared, typed variables. Render
+// checks its input against them before rendering, so a missing or
+// mistyped variable is an error instead of a silent "<no value>" in the
+// prompt.
+type PromptSpec struct {
+	tmpl *template.Template
+	vars []PromptVar
+}
+
+// NewPromptSpec parses text as a text/template, referring to variables as
+// {{.Name}}, e.g.
+//
+//	spec, err := NewPromptSpec("A {{.Title}} page listing {{.ItemCount}} items",
+//		PromptVar{Name: "Title", Kind: reflect.String},
+//		PromptVar{Name: "ItemCount", Kind: reflect.Int},
+//	)
+func NewPromptSpec(text string, vars ...PromptVar) (*PromptSpec, error) {
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt template: %w", err)
+	}
+	return &PromptSpec{tmpl: tmpl, vars: slices.Clone(vars)}, nil
+}
+
+// Render validates vars against the declared variables and renders the
+// prompt. Every problem found is reported, not just the first.
+func (s *PromptSpec) Render(vars map[string]any) (string, error) {
+	var problems []string
+	data := make(map[string]any, len(s.vars))
+	for _, v := range s.vars {
+		value, ok := vars[v.Name]
+		switch {
+		case !ok && v.Optional:
+			data[v.Name] = ""
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s is required", v.Name))
+		case v.Kind != reflect.Invalid && reflect.ValueOf(value).Kind() != v.Kind:
+			problems = append(problems, fmt.Sprintf("%s must be of kind %s, got %T", v.Name, v.Kind, value))
+		default:
+			data[v.Name] = value
+		}
+	}
+	for name := range vars {
+		if !slices.ContainsFunc(s.vars, func(v PromptVar) bool { return v.Name == name }) {
+			problems = append(problems, fmt.Sprintf("%s is not declared", name))
+		}
+	}
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return "", fmt.Errorf("%w: %s", ErrPromptVariables, strings.Join(problems, "; "))
+	}
+
+	var out strings.Builder
+	if err := s.tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt: %w", err)
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPromptSpec_Render(t *testing.T) {
+	spec, err := NewPromptSpec("A {{.Title}} page listing {{.ItemCount}} items{{.Note}}",
+		PromptVar{Name: "Title", Kind: reflect.String},
+		PromptVar{Name: "ItemCount", Kind: reflect.Int},
+		PromptVar{Name: "Note", Optional: true},
+	)
+	if err != nil {
+		t.Fatalf("NewPromptSpec failed: %v", err)
+	}
+
+	got, err := spec.Render(map[string]any{"Title": "todo", "ItemCount": 3})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if got != "A todo page listing 3 items" {
+		t.Errorf("got %q", got)
+	}
+
+	_, err = spec.Render(map[string]any{"ItemCount": "three", "Colour": "red"})
+	if !errors.Is(err, ErrPromptVariables) {
+		t.Fatalf("expected ErrPromptVariables, got %v", err)
+	}
+	for _, want := range []string{"Title is required", "ItemCount must be of kind int, got string", "Colour is not declared"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q missing %q", err, want)
+		}
+	}
+}
+
+func TestPromptSpec_UndeclaredReference(t *testing.T) {
+	spec, err := NewPromptSpec("A {{.Title}} page")
+	if err != nil {
+		t.Fatalf("NewPromptSpec failed: %v", err)
+	}
+	if _, err := spec.Render(nil); err == nil || strings.Contains(err.Error(), "<no value>") {
+		t.Errorf("expected an error for an undeclared variable, got %v", err)
+	}
+
+	if _, err := NewPromptSpec("A {{.Title page"); err == nil {
+		t.Error("expected a parse error")
+	}
+}
@@ -0,0 +1,43 @@
+package llm
+
+import "strings"
+
+// QualityProfile toggles standard quality directives that are appended to
+// the system prompt.
+type QualityProfile struct {
+	Responsive          bool
+	Accessible          bool
+	NoExternalResources bool
+	DarkMode            bool
+}
+
+// Directives returns the system prompt additions for the enabled toggles,
+// or "" when none are.
+func (p QualityProfile) Directives() string {
+	var rules []string
+	if p.Responsive {
+		rules = append(rules, "- Make the layout responsive: mobile-first CSS, fluid widths, a viewport meta tag, and media queries for larger screens")
+	}
+	if p.Accessible {
+		rules = append(rules, "- Make it accessible: semantic HTML5 elements, labelled form controls, alt text on images, ARIA attributes where native semantics fall short, visible focus styles and sufficient color contrast")
+	}
+	if p.NoExternalResources {
+		rules = append(rules, "- Do not load anything external: no CDNs, web fonts, remote images or scripts; everything must be inline")
+	}
+	if p.DarkMode {
+		rules = append(rules, "- Support dark mode with prefers-color-scheme and CSS custom properties for the colors")
+	}
+	if len(rules) == 0 {
+		return ""
+	}
+
+	return "Quality requirements:\n" + strings.Join(rules, "\n")
+}
+
+// WithQualityProfile appends profile's directives to the system prompt. It
+// has no effect with WithDisableSystemPrompt.
+func WithQualityProfile(profile QualityProfile) Option {
+	return func(o *options) {
+		o.qualityProfile = profile
+	}
+}
@@ -0,0 +1,42 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestQualityProfile_Directives(t *testing.T) {
+	if got := (QualityProfile{}).Directives(); got != "" {
+		t.Errorf("empty profile should add nothing, got %q", got)
+	}
+
+	got := QualityProfile{Responsive: true, NoExternalResources: true}.Directives()
+	for _, want := range []string{"responsive", "no CDNs"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("directives missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "ARIA") || strings.Contains(got, "dark mode") {
+		t.Errorf("directives include disabled toggles:\n%s", got)
+	}
+}
+
+func TestWithQualityProfile(t *testing.T) {
+	o := defaultOptions()
+	WithQualityProfile(QualityProfile{Accessible: true, DarkMode: true})(&o)
+
+	messages, _ := o.buildMessages(context.Background(), "a todo app")
+	system := messages[0].Content
+	if !strings.HasPrefix(system, systemPrompt) {
+		t.Error("base system prompt should come first")
+	}
+	if !strings.Contains(system, "ARIA") || !strings.Contains(system, "prefers-color-scheme") {
+		t.Errorf("system prompt missing directives:\n%s", system)
+	}
+
+	WithDisableSystemPrompt()(&o)
+	if messages, _ := o.buildMessages(context.Background(), "a todo app"); len(messages) != 1 || messages[0].Role != RoleUser {
+		t.Errorf("expected only the user message, got %+v", messages)
+	}
+}
@@ -0,0 +1,44 @@
+package llm
+
+import "strings"
+
+type ReasoningEffort string
+
+const (
+	ReasoningLow    ReasoningEffort = "low"
+	ReasoningMedium ReasoningEffort = "medium"
+	ReasoningHigh   ReasoningEffort = "high"
+)
+
+var reasoningModelPrefixes = []string{"o1", "o3", "o4"}
+
+func (o *options) isReasoningModel(model string) bool {
+	if o.reasoningModel != nil {
+		return *o.reasoningModel
+	}
+
+	for _, prefix := range reasoningModelPrefixes {
+		if model == prefix || strings.HasPrefix(model, prefix+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// WithReasoningModel overrides reasoning-model detection, which otherwise
+// goes by the o1/o3/o4 name prefix. Reasoning models are sent
+// max_completion_tokens and reasoning_effort instead of max_tokens and
+// temperature.
+func WithReasoningModel(enabled bool) Option {
+	return func(o *options) {
+		o.reasoningModel = &enabled
+	}
+}
+
+// WithReasoningEffort sets reasoning_effort for reasoning models. It is
+// ignored for other models.
+func WithReasoningEffort(effort ReasoningEffort) Option {
+	return func(o *options) {
+		o.reasoningEffort = effort
+	}
+}
@@ -0,0 +1,64 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestOpenAIClient_ReasoningModelRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		model         string
+		opts          []Option
+		wantReasoning bool
+	}{
+		{"o1", "o1", nil, true},
+		{"o3-mini", "o3-mini", nil, true},
+		{"o4-mini dated", "o4-mini-2025-04-16", nil, true},
+		{"gpt-4o", "gpt-4o", nil, false},
+		{"explicit flag", "my-reasoner", []Option{WithReasoningModel(true)}, true},
+		{"explicit off", "o1", []Option{WithReasoningModel(false)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]any
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+					return
+				}
+
+				_, hasTemperature := body["temperature"]
+				_, hasMaxTokens := body["max_tokens"]
+				_, hasMaxCompletion := body["max_completion_tokens"]
+				effort, _ := body["reasoning_effort"].(string)
+
+				if tt.wantReasoning {
+					if hasTemperature || hasMaxTokens || !hasMaxCompletion || effort != "high" {
+						t.Errorf("unexpected reasoning request: %v", body)
+					}
+				} else {
+					if !hasTemperature || !hasMaxTokens || hasMaxCompletion || effort != "" {
+						t.Errorf("unexpected chat request: %v", body)
+					}
+				}
+				writeChatResponse(w, "ok")
+			})
+
+			opts := append([]Option{
+				WithBaseURL(server.URL),
+				WithTemperature(0.5),
+				WithMaxTokens(2000),
+				WithReasoningEffort(ReasoningHigh),
+			}, tt.opts...)
+			client := NewOpenAIClient("test-key", tt.model, opts...)
+
+			if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+				t.Fatalf("GenerateCode failed: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package llm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// bodySnippetLen caps how much of an unexpected body goes into errors.
+const bodySnippetLen = 200
+
+// newHTTPClient returns the client's transport. It sets no Timeout, calls
+// are bounded by callContext instead.
+func (o *options) newHTTPClient() *http.Client {
+	client := &http.Client{}
+	if o.proxyURL != nil || o.insecureSkipVerify {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if o.proxyURL != nil {
+			transport.Proxy = http.ProxyURL(o.proxyURL)
+		}
+		if o.insecureSkipVerify {
+			transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		}
+		client.Transport = transport
+	}
+	if o.maxConcurrent > 0 {
+		base := client.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		client.Transport = &semaphoreTransport{base: base, sem: semaphore.NewWeighted(int64(o.maxConcurrent))}
+	}
+	if o.traceWriter != nil {
+		base := client.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		client.Transport = &traceTransport{base: base, w: o.traceWriter}
+	}
+	return client
+}
+
+// newJSONRequest builds a request carrying body as JSON, applying extra
+// params, compression and auth, and dumping it when enabled.
+func (o *options) newJSONRequest(ctx context.Context, method, url string, body any, authorize func(*http.Request) error) (*http.Request, error) {
+	jsonData, err := o.marshalBody(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return o.newRawJSONRequest(ctx, method, url, jsonData, authorize)
+}
+
+// newRawJSONRequest is newJSONRequest for an already marshaled body, to
+// which extra params are not added.
+func (o *options) newRawJSONRequest(ctx context.Context, method, url string, jsonData []byte, authorize func(*http.Request) error) (*http.Request, error) {
+	if o.proxyErr != nil {
+		return nil, o.proxyErr
+	}
+	if o.chatMethodErr != nil {
+		return nil, o.chatMethodErr
+	}
+
+	var err error
+	rawBody := jsonData
+	compressed := o.compressRequests && len(jsonData) >= gzipMinBytes
+	if compressed {
+		jsonData, err = gzipBytes(jsonData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compress request: %w", err)
+		}
+	}
+
+	bufferJson := bytes.NewReader(jsonData)
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bufferJson)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Idempotency-Key", requestIdempotencyKey(ctx, method, url, rawBody))
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if err := authorize(req); err != nil {
+		return nil, err
+	}
+
+	o.dumpRequest(ctx, req, rawBody)
+	return req, nil
+}
+
+// doJSONRequest sends req via doWithRetry. A gateway that rejects a
+// gzipped body with 415 gets the request once more uncompressed.
+func doJSONRequest(client *http.Client, req *http.Request, policy retryPolicy) (*http.Response, error) {
+	resp, err := doWithRetry(client, req, policy)
+	if err != nil || resp.StatusCode != http.StatusUnsupportedMediaType || req.Header.Get("Content-Encoding") != "gzip" {
+		return resp, err
+	}
+
+	plain, err := uncompressedRequest(req)
+	if err != nil {
+		return resp, nil
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	return doWithRetry(client, plain, policy)
+}
+
+// uncompressedRequest clones a gzipped req with its body decompressed.
+func uncompressedRequest(req *http.Request) (*http.Request, error) {
+	if req.GetBody == nil {
+		return nil, errors.New("request body is not replayable")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	zr, err := gzip.NewReader(body)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	plain := req.Clone(req.Context())
+	plain.Header.Del("Content-Encoding")
+	plain.ContentLength = int64(len(data))
+	plain.Body = io.NopCloser(bytes.NewReader(data))
+	plain.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	return plain, nil
+}
+
+// checkContentType returns ErrUnexpectedContentType when resp isn't of the
+// wanted media type, e.g. an auth proxy answering with an HTML login page.
+// A missing Content-Type is let through. JSON accepts any +json subtype.
+func checkContentType(resp *http.Response, want string) error {
+	header := resp.Header.Get("Content-Type")
+	if header == "" {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err == nil && (mediaType == want || (want == "application/json" && strings.HasSuffix(mediaType, "+json"))) {
+		return nil
+	}
+
+	return fmt.Errorf("%w %q (want %s)", ErrUnexpectedContentType, header, want)
+}
+
+func bodySnippet(body []byte) string {
+	if len(body) <= bodySnippetLen {
+		return string(body)
+	}
+
+	cut := bodySnippetLen
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+	return string(body[:cut]) + "..."
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
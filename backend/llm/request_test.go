@@ -0,0 +1,123 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenAIClient_UnexpectedContentType(t *testing.T) {
+	loginPage := "<!DOCTYPE html><html><body><form action=\"/login\">Sign in to continue</form>" + strings.Repeat("<p>filler</p>", 50) + "</body></html>"
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(loginPage))
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	_, err := client.GenerateCode(context.Background(), "hello")
+	if !errors.Is(err, ErrUnexpectedContentType) {
+		t.Fatalf("expected ErrUnexpectedContentType, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "text/html") || !strings.Contains(err.Error(), "Sign in to continue") {
+		t.Errorf("error should name the content type and include a body snippet: %v", err)
+	}
+	if strings.Contains(err.Error(), "</body>") {
+		t.Errorf("body snippet should be truncated: %v", err)
+	}
+
+	var out strings.Builder
+	if err := client.GenerateCodeStream(context.Background(), "hello", &out); !errors.Is(err, ErrUnexpectedContentType) {
+		t.Errorf("stream: expected ErrUnexpectedContentType, got %v", err)
+	}
+}
+
+func TestCheckContentType(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+		ok     bool
+	}{
+		{"", "application/json", true},
+		{"application/json", "application/json", true},
+		{"application/json; charset=utf-8", "application/json", true},
+		{"application/problem+json", "application/json", true},
+		{"text/html", "application/json", false},
+		{"text/event-stream", "text/event-stream", true},
+		{"application/json", "text/event-stream", false},
+	}
+
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if tt.header != "" {
+			resp.Header.Set("Content-Type", tt.header)
+		}
+		if err := checkContentType(resp, tt.want); (err == nil) != tt.ok {
+			t.Errorf("checkContentType(%q, %q) = %v, want ok=%v", tt.header, tt.want, err, tt.ok)
+		}
+	}
+}
+
+func TestOpenAIClient_WithProxy(t *testing.T) {
+	var proxied string
+	proxy := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		writeChatResponse(w, "ok")
+	})
+
+	client := NewOpenAIClient("test-key", "gpt-4",
+		WithBaseURL("http://api.example.invalid/v1"),
+		WithProxy(proxy.URL),
+		WithTimeout(5*time.Second),
+	)
+
+	if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	if proxied != "http://api.example.invalid/v1/chat/completions" {
+		t.Errorf("proxy got request for %q", proxied)
+	}
+}
+
+func TestOpenAIClient_WithProxyInvalid(t *testing.T) {
+	client := NewOpenAIClient("test-key", "gpt-4", WithProxy("proxy.corp:3128"))
+
+	_, err := client.GenerateCode(context.Background(), "hello")
+	if err == nil || !strings.Contains(err.Error(), "invalid proxy URL") {
+		t.Fatalf("expected invalid proxy URL error, got %v", err)
+	}
+}
+
+func TestOpenAIClient_WithInsecureSkipTLSVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeChatResponse(w, "ok")
+	}))
+	t.Cleanup(server.Close)
+
+	strict := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+	if _, err := strict.GenerateCode(context.Background(), "hello"); err == nil {
+		t.Fatal("expected certificate error without WithInsecureSkipTLSVerify")
+	}
+
+	insecure := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithInsecureSkipTLSVerify(true))
+	if _, err := insecure.GenerateCode(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+}
+
+func TestOpenAIClient_UserAgent(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if ua := r.Header.Get("User-Agent"); ua != "scaffolder-llm/"+Version {
+			t.Errorf("unexpected User-Agent %q", ua)
+		}
+		writeChatResponse(w, "ok")
+	})
+
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+	if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+}
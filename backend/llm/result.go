@@ -0,0 +1,59 @@
+package llm
+
+import "strings"
+
+// GenerationResult is the full outcome of a generation.
+//
+// Reasoning holds any reasoning trace the model returned, kept out of Code
+// so it can't leak into the generated file. It is taken from the message's
+// reasoning_content or reasoning field when the provider sends one
+// separately, otherwise from a leading <think>...</think> block in the
+// content (as emitted by DeepSeek-R1 style models).
+//
+// Raw is the message content exactly as the provider returned it, before
+// any reasoning block is split off or the output is checked.
+//
+// Usage is the token usage the provider reported, zero if it sent none.
+//
+// Model is the model the request was sent to, after any WithModelRouter
+// routing, alias resolution and WithFallbackModel fallback. Meta.Model is
+// the one the response names, where a provider reports it.
+//
+// ServiceTier is the OpenAI service tier that processed the request, if
+// the response named one.
+//
+// Meta describes the HTTP response the result came from, for clients that
+// record it.
+//
+// SentMessages are the messages as finally sent, system prompt and
+// pre-processing included. They are only recorded with
+// WithCaptureSentMessages.
+type GenerationResult struct {
+	Code         string
+	Reasoning    string
+	Raw          string
+	Usage        Usage
+	Model        string
+	ServiceTier  ServiceTier
+	Meta         *ResponseMeta
+	SentMessages []Message
+}
+
+// splitReasoning separates a leading <think> block from the final answer.
+func splitReasoning(content string) (reasoning, answer string) {
+	const open, close = "<think>", "</think>"
+
+	trimmed := strings.TrimLeft(content, " \t\r\n")
+	if !strings.HasPrefix(trimmed, open) {
+		return "", content
+	}
+
+	end := strings.Index(trimmed, close)
+	if end < 0 {
+		return "", content
+	}
+
+	reasoning = strings.TrimSpace(trimmed[len(open):end])
+	answer = strings.TrimLeft(trimmed[end+len(close):], " \t\r\n")
+	return reasoning, answer
+}
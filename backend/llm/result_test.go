@@ -0,0 +1,49 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestSplitReasoning(t *testing.T) {
+	tests := []struct {
+		content       string
+		wantReasoning string
+		wantAnswer    string
+	}{
+		{"<html></html>", "", "<html></html>"},
+		{"<think>plan the layout</think>\n\n```html\n<p></p>\n```", "plan the layout", "```html\n<p></p>\n```"},
+		{"  \n<think>\nstep 1\nstep 2\n</think>answer", "step 1\nstep 2", "answer"},
+		{"<think>never closed", "", "<think>never closed"},
+		{"text before <think>x</think>", "", "text before <think>x</think>"},
+	}
+
+	for _, tt := range tests {
+		reasoning, answer := splitReasoning(tt.content)
+		if reasoning != tt.wantReasoning || answer != tt.wantAnswer {
+			t.Errorf("splitReasoning(%q) = (%q, %q), want (%q, %q)", tt.content, reasoning, answer, tt.wantReasoning, tt.wantAnswer)
+		}
+	}
+}
+
+func TestOpenAIClient_GenerateSeparatesReasoning(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"<html></html>","reasoning_content":"think about it"}}]}`))
+	})
+	client := NewOpenAIClient("test-key", "deepseek-reasoner", WithBaseURL(server.URL))
+
+	result, err := client.Generate(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if result.Code != "<html></html>" || result.Reasoning != "think about it" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	code, err := client.GenerateCode(context.Background(), "hello")
+	if err != nil || code != "<html></html>" {
+		t.Errorf("GenerateCode = %q, %v", code, err)
+	}
+}
@@ -0,0 +1,161 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultRetryBaseDelay = 500 * time.Millisecond
+	defaultRetryMaxDelay  = 30 * time.Second
+)
+
+// JitterStrategy controls how retry backoff delays are randomized.
+type JitterStrategy int
+
+const (
+	// JitterFull picks a delay uniformly in [0, backoff).
+	JitterFull JitterStrategy = iota
+	// JitterEqual keeps half the backoff and randomizes the other half.
+	JitterEqual
+	// JitterNone uses the exact exponential backoff.
+	JitterNone
+)
+
+type retryPolicy struct {
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	jitter     JitterStrategy
+	retryIf    func(resp *http.Response, err error) bool
+	clock      Clock
+
+	// baseURL is the client's base URL, which failover endpoints replace.
+	baseURL               string
+	endpoints             []Endpoint
+	failoverStrategy      FailoverStrategy
+	maxRetriesPerEndpoint *int
+}
+
+func (p retryPolicy) backoff(attempt int, rng *rand.Rand) time.Duration {
+	delay := p.baseDelay << attempt
+	if delay <= 0 || delay > p.maxDelay {
+		delay = p.maxDelay
+	}
+
+	switch p.jitter {
+	case JitterNone:
+		return delay
+	case JitterEqual:
+		half := delay / 2
+		return half + time.Duration(rng.Int64N(int64(half)+1))
+	default:
+		return time.Duration(rng.Int64N(int64(delay) + 1))
+	}
+}
+
+func (p retryPolicy) shouldRetry(resp *http.Response, err error) bool {
+	if p.retryIf == nil {
+		return shouldRetry(resp, err)
+	}
+
+	// Let the predicate read error bodies without consuming them for the
+	// caller. Successful bodies are left alone since they may be streams.
+	if resp != nil && resp.StatusCode >= 300 {
+		body, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if readErr != nil {
+			return true
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		defer func() { resp.Body = io.NopCloser(bytes.NewReader(body)) }()
+	}
+
+	return p.retryIf(resp, err)
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// doWithRetry sends req, retrying transient failures per policy and
+// failing over between its endpoints. The request body must be replayable
+// via GetBody.
+func doWithRetry(client *http.Client, req *http.Request, policy retryPolicy) (*http.Response, error) {
+	ctx := req.Context()
+	// Seeded per call so concurrent callers don't back off in lockstep.
+	clock := clockOrReal(policy.clock)
+	rng := rand.New(rand.NewPCG(rand.Uint64(), uint64(clock.Now().UnixNano())))
+
+	steps := policy.plan()
+	for i, step := range steps {
+		attemptReq := req
+		if i > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(ctx)
+			attemptReq.Body = body
+		}
+		if step.endpoint >= 0 {
+			u, err := policy.endpointURL(req.URL, step.endpoint)
+			if err != nil {
+				return nil, fmt.Errorf("invalid failover endpoint: %w", err)
+			}
+			if attemptReq == req {
+				attemptReq = req.Clone(ctx)
+			}
+			attemptReq.URL, attemptReq.Host = u, ""
+		}
+
+		sent := clock.Now()
+		resp, err := client.Do(attemptReq)
+		if i == len(steps)-1 || ctx.Err() != nil || !policy.shouldRetry(resp, err) {
+			return resp, err
+		}
+
+		// Don't wait out a backoff whose retry can't finish before the
+		// deadline; the caller gets the last failure instead.
+		var delay time.Duration
+		if next := steps[i+1]; next.backoff >= 0 {
+			delay = policy.backoff(next.backoff, rng)
+		}
+		now := clock.Now()
+		if deadline, ok := ctx.Deadline(); ok && deadline.Sub(now) < delay+now.Sub(sent) {
+			return resp, err
+		}
+		if !takeRetry(ctx) {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if delay > 0 {
+			if err := sleepContext(ctx, clock, delay); err != nil {
+				return nil, err
+			}
+		}
+	}
+	panic("unreachable")
+}
+
+func sleepContext(ctx context.Context, clock Clock, d time.Duration) error {
+	select {
+	case <-clock.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,157 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoffJitter(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	policy := retryPolicy{baseDelay: 100 * time.Millisecond, maxDelay: time.Second}
+
+	tests := []struct {
+		name     string
+		jitter   JitterStrategy
+		attempt  int
+		min, max time.Duration
+	}{
+		{"none first attempt", JitterNone, 0, 100 * time.Millisecond, 100 * time.Millisecond},
+		{"none capped", JitterNone, 10, time.Second, time.Second},
+		{"equal", JitterEqual, 2, 200 * time.Millisecond, 400 * time.Millisecond},
+		{"full", JitterFull, 3, 0, 800 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy.jitter = tt.jitter
+			for i := 0; i < 100; i++ {
+				d := policy.backoff(tt.attempt, rng)
+				if d < tt.min || d > tt.max {
+					t.Fatalf("backoff %v outside [%v, %v]", d, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenAIClient_RetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		writeChatResponse(w, "ok")
+	})
+
+	client := NewOpenAIClient("test-key", "gpt-4",
+		WithBaseURL(server.URL),
+		WithRetries(2),
+		WithBackoff(time.Millisecond, 5*time.Millisecond),
+	)
+
+	result, err := client.GenerateCode(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	if result != "ok" || calls.Load() != 3 {
+		t.Errorf("result=%q calls=%d", result, calls.Load())
+	}
+}
+
+func TestOpenAIClient_DoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "bad request", http.StatusBadRequest)
+	})
+
+	client := NewOpenAIClient("test-key", "gpt-4",
+		WithBaseURL(server.URL),
+		WithRetries(3),
+		WithBackoff(time.Millisecond, 5*time.Millisecond),
+	)
+
+	if _, err := client.GenerateCode(context.Background(), "hello"); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected 1 call, got %d", calls.Load())
+	}
+}
+
+func TestOpenAIClient_RetryIf(t *testing.T) {
+	var calls atomic.Int32
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			http.Error(w, "tenant quota", 499)
+		case 2:
+			http.Error(w, `{"error":"context_length_exceeded"}`, http.StatusBadRequest)
+		default:
+			writeChatResponse(w, "ok")
+		}
+	})
+
+	retryIf := func(resp *http.Response, err error) bool {
+		if err != nil {
+			return true
+		}
+		if resp.StatusCode == 499 {
+			return true
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return !strings.Contains(string(body), "context_length_exceeded")
+	}
+
+	client := NewOpenAIClient("test-key", "gpt-4",
+		WithBaseURL(server.URL),
+		WithRetries(5),
+		WithBackoff(time.Millisecond, 5*time.Millisecond),
+		WithRetryIf(retryIf),
+	)
+
+	_, err := client.GenerateCode(context.Background(), "hello")
+	if err == nil || !strings.Contains(err.Error(), "context_length_exceeded") {
+		t.Fatalf("expected non-retried 400 with body preserved, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected 2 calls (499 retried, 400 not), got %d", calls.Load())
+	}
+}
+
+func TestOpenAIClient_RetrySkipsDoomedBackoff(t *testing.T) {
+	var calls atomic.Int32
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	})
+
+	client := NewOpenAIClient("test-key", "gpt-4",
+		WithBaseURL(server.URL),
+		WithRetries(3),
+		WithBackoff(time.Second, time.Second),
+		WithJitter(JitterNone),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.GenerateCode(ctx, "hello")
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("waited %v on a backoff past the deadline", elapsed)
+	}
+	if err == nil || !strings.Contains(err.Error(), "status 503") {
+		t.Errorf("expected the last 503, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected 1 attempt, got %d", calls.Load())
+	}
+}
@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// RetryBudget is a pool of retries shared by every call whose context
+// carries it, e.g. the items of a batch. When a rate-limited API starts
+// failing the whole batch at once, the retries stop once the pool is spent
+// instead of multiplying with the batch size: further failures are
+// returned straight away. A budget is safe for concurrent use.
+type RetryBudget struct {
+	remaining atomic.Int64
+}
+
+// NewRetryBudget returns a budget of n retries in total.
+func NewRetryBudget(n int) *RetryBudget {
+	b := &RetryBudget{}
+	b.remaining.Store(int64(max(n, 0)))
+	return b
+}
+
+// Remaining returns how many retries are left.
+func (b *RetryBudget) Remaining() int {
+	return int(b.remaining.Load())
+}
+
+// take spends one retry, reporting false if none are left.
+func (b *RetryBudget) take() bool {
+	for {
+		n := b.remaining.Load()
+		if n <= 0 {
+			return false
+		}
+		if b.remaining.CompareAndSwap(n, n-1) {
+			return true
+		}
+	}
+}
+
+type retryBudgetKey struct{}
+
+// WithRetryBudget returns a context that makes client calls using it draw
+// their retries, failover attempts and regenerations of rejected output
+// included, from b. A call still makes at most as many retries as its
+// client's WithRetries allows.
+func WithRetryBudget(ctx context.Context, b *RetryBudget) context.Context {
+	return context.WithValue(ctx, retryBudgetKey{}, b)
+}
+
+// takeRetry spends a retry from ctx's budget, if it carries one.
+func takeRetry(ctx context.Context) bool {
+	b, ok := ctx.Value(retryBudgetKey{}).(*RetryBudget)
+	return !ok || b.take()
+}
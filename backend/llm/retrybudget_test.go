@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryBudget_SharedAcrossCalls(t *testing.T) {
+	var calls atomic.Int32
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	})
+	client := NewOpenAIClient("test-key", "gpt-4",
+		WithBaseURL(server.URL),
+		WithRetries(3),
+		WithBackoff(time.Millisecond, time.Millisecond),
+	)
+
+	budget := NewRetryBudget(4)
+	ctx := WithRetryBudget(context.Background(), budget)
+
+	const items = 10
+	var wg sync.WaitGroup
+	for i := 0; i < items; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GenerateCode(ctx, "hello"); err == nil {
+				t.Error("expected the rate limit error")
+			}
+		}()
+	}
+	wg.Wait()
+
+	// One attempt per item plus the shared retries, rather than the
+	// 4 attempts each the client would otherwise make.
+	if n := calls.Load(); n != items+4 {
+		t.Errorf("expected %d requests, got %d", items+4, n)
+	}
+	if budget.Remaining() != 0 {
+		t.Errorf("expected the budget to be spent, %d left", budget.Remaining())
+	}
+}
+
+func TestRetryBudget_Take(t *testing.T) {
+	b := NewRetryBudget(2)
+	if !b.take() || !b.take() || b.take() {
+		t.Error("expected exactly two retries")
+	}
+	if NewRetryBudget(-1).take() {
+		t.Error("a negative budget should allow no retries")
+	}
+}
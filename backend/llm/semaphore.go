@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"io"
+	"net/http"
+	"sync"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// WithMaxConcurrent caps the client's in-flight HTTP requests at n,
+// independent of any request rate limit, to protect downstream connection
+// pools. A request beyond the cap blocks until a slot frees up or its
+// context ends. A slot is held until the response body is closed or read to
+// the end, so a stream occupies one for its whole duration. Zero or less
+// means no cap.
+func WithMaxConcurrent(n int) Option {
+	return func(o *options) {
+		o.maxConcurrent = n
+	}
+}
+
+type semaphoreTransport struct {
+	base http.RoundTripper
+	sem  *semaphore.Weighted
+}
+
+func (t *semaphoreTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.sem.Acquire(req.Context(), 1); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		t.sem.Release(1)
+		return nil, err
+	}
+	resp.Body = &semaphoreBody{ReadCloser: resp.Body, release: sync.OnceFunc(func() { t.sem.Release(1) })}
+	return resp, nil
+}
+
+// semaphoreBody releases its slot once the body is closed or exhausted.
+type semaphoreBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *semaphoreBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.release()
+	}
+	return n, err
+}
+
+func (b *semaphoreBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,77 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOpenAIClient_MaxConcurrent(t *testing.T) {
+	var inflight, peak atomic.Int32
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		writeChatResponse(w, "ok")
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithMaxConcurrent(2))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+				t.Errorf("GenerateCode failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got != 2 {
+		t.Errorf("peak concurrency = %d, want 2", got)
+	}
+}
+
+func TestOpenAIClient_MaxConcurrentRespectsContext(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		writeChatResponse(w, "ok")
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithMaxConcurrent(1))
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := client.GenerateCode(context.Background(), "first")
+		errc <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.GenerateCode(ctx, "second"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the queued call to time out, got %v", err)
+	}
+
+	close(release)
+	if err := <-errc; err != nil {
+		t.Errorf("first call failed: %v", err)
+	}
+}
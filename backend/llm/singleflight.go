@@ -0,0 +1,64 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// SingleflightProvider collapses concurrent identical GenerateCode calls
+// into one upstream request whose result every caller receives. Nothing is
+// cached once the request completes, errors included.
+//
+// The shared request isn't cancelled when one caller's context is; each
+// caller stops waiting on its own context while the request carries on for
+// the others.
+//
+// Calls only share a request if their idempotency keys and per-call
+// timeouts match, as those are read from the context. Any other context
+// values, e.g. those a WithSystemPromptFunc callback reads, are taken from
+// whichever caller started the shared request.
+type SingleflightProvider struct {
+	provider Provider
+	group    singleflight.Group
+}
+
+func NewSingleflightProvider(p Provider) *SingleflightProvider {
+	return &SingleflightProvider{provider: p}
+}
+
+func (s *SingleflightProvider) GenerateCode(ctx context.Context, prompt string) (string, error) {
+	ch := s.group.DoChan(requestKey(ctx, prompt), func() (any, error) {
+		return s.provider.GenerateCode(context.WithoutCancel(ctx), prompt)
+	})
+
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return "", res.Err
+		}
+		return res.Val.(string), nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// requestKey identifies a call by its prompt and the context values the
+// clients read.
+func requestKey(ctx context.Context, prompt string) string {
+	h := sha256.New()
+	key, _ := ctx.Value(idempotencyKey{}).(string)
+	timeout, hasTimeout := ctx.Value(perCallTimeoutKey{}).(time.Duration)
+	lang, _ := ctx.Value(languageKey{}).(string)
+	// Calls recording their model or drawing on a retry budget are only
+	// shared with calls using the same recorder and budget.
+	recorder, _ := ctx.Value(modelRecorderKey{}).(*string)
+	budget, _ := ctx.Value(retryBudgetKey{}).(*RetryBudget)
+	fmt.Fprintf(h, "%q %v %d %q %p %p\n", key, hasTimeout, timeout, lang, recorder, budget)
+	h.Write([]byte(prompt))
+	return hex.EncodeToString(h.Sum(nil))
+}
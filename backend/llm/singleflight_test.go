@@ -0,0 +1,114 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingProvider counts calls and blocks each one until release is closed.
+type blockingProvider struct {
+	calls   atomic.Int32
+	release chan struct{}
+	result  string
+	err     error
+}
+
+func (b *blockingProvider) GenerateCode(ctx context.Context, prompt string) (string, error) {
+	b.calls.Add(1)
+	<-b.release
+	return b.result + prompt, b.err
+}
+
+func TestSingleflightProvider_SharesInFlightRequests(t *testing.T) {
+	upstream := &blockingProvider{release: make(chan struct{}), result: "code:"}
+	provider := NewSingleflightProvider(upstream)
+
+	const callers = 10
+	var started, wg sync.WaitGroup
+	results := make([]string, callers)
+	for i := 0; i < callers; i++ {
+		started.Add(1)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			started.Done()
+			results[i], _ = provider.GenerateCode(context.Background(), "same")
+		}(i)
+	}
+
+	// Give every caller time to join the in-flight request.
+	started.Wait()
+	time.Sleep(50 * time.Millisecond)
+	close(upstream.release)
+	wg.Wait()
+
+	if upstream.calls.Load() != 1 {
+		t.Errorf("expected 1 upstream call, got %d", upstream.calls.Load())
+	}
+	for i, r := range results {
+		if r != "code:same" {
+			t.Errorf("caller %d got %q", i, r)
+		}
+	}
+}
+
+func TestSingleflightProvider_DoesNotCacheErrors(t *testing.T) {
+	upstream := &blockingProvider{release: make(chan struct{}), err: errors.New("boom")}
+	close(upstream.release)
+	provider := NewSingleflightProvider(upstream)
+
+	for i := 0; i < 2; i++ {
+		if _, err := provider.GenerateCode(context.Background(), "same"); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	if upstream.calls.Load() != 2 {
+		t.Errorf("expected errors not to be cached, got %d calls", upstream.calls.Load())
+	}
+}
+
+func TestSingleflightProvider_CallerCancellation(t *testing.T) {
+	upstream := &blockingProvider{release: make(chan struct{})}
+	defer close(upstream.release)
+	provider := NewSingleflightProvider(upstream)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := provider.GenerateCode(ctx, "same"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestSingleflightProvider_KeyIncludesContextValues(t *testing.T) {
+	upstream := &blockingProvider{release: make(chan struct{}), result: "code:"}
+	provider := NewSingleflightProvider(upstream)
+
+	ctxs := []context.Context{
+		WithIdempotencyKey(context.Background(), "job-1"),
+		WithIdempotencyKey(context.Background(), "job-2"),
+		WithPerCallTimeout(context.Background(), time.Minute),
+		WithLanguage(context.Background(), "German"),
+		WithLanguage(context.Background(), "French"),
+	}
+	var wg sync.WaitGroup
+	for _, ctx := range ctxs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			provider.GenerateCode(ctx, "same")
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(upstream.release)
+	wg.Wait()
+
+	if upstream.calls.Load() != int32(len(ctxs)) {
+		t.Errorf("calls with different context values should not be shared, got %d upstream calls", upstream.calls.Load())
+	}
+}
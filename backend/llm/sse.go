@@ -0,0 +1,85 @@
+package llm
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+// errStreamDone is returned by an SSE handler to stop reading early.
+var errStreamDone = errors.New("stream done")
+
+// sseReadError marks a failure reading the stream itself, as opposed to an
+// error returned by the event handler.
+type sseReadError struct {
+	err error
+}
+
+func (e *sseReadError) Error() string { return e.err.Error() }
+func (e *sseReadError) Unwrap() error { return e.err }
+
+// readSSE calls handle with the data payload of each server-sent event
+// until the stream ends, handle returns an error, or a "[DONE]" sentinel
+// is received.
+func readSSE(r io.Reader, handle func(data string) error) error {
+	_, err := scanSSE(r, handle)
+	return err
+}
+
+// readSSEUntilDone is readSSE for streams that must end with "[DONE]".
+// Reaching EOF without it means the connection was cut short, which is
+// reported as an *sseReadError so the stream can be reconnected.
+func readSSEUntilDone(r io.Reader, handle func(data string) error) error {
+	done, err := scanSSE(r, handle)
+	if err == nil && !done {
+		return &sseReadError{err: io.ErrUnexpectedEOF}
+	}
+	return err
+}
+
+// scanSSE implements readSSE, reporting whether the stream was ended by
+// "[DONE]" or by handle returning errStreamDone.
+//
+// Events are framed as in the SSE spec: lines up to a blank line form one
+// event, the values of its data fields are joined with newlines, and
+// comment lines starting with ":" (gateway keep-alives) and other fields
+// are ignored. An event cut off by the end of the stream is dropped.
+func scanSSE(r io.Reader, handle func(data string) error) (done bool, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" {
+			if field, value, ok := strings.Cut(line, ":"); ok && field == "data" {
+				data = append(data, strings.TrimPrefix(value, " "))
+			} else if line == "data" {
+				data = append(data, "")
+			}
+			continue
+		}
+		if data == nil {
+			continue
+		}
+
+		event := strings.Join(data, "\n")
+		data = nil
+		if strings.TrimSpace(event) == "[DONE]" {
+			return true, nil
+		}
+
+		if err := handle(event); err != nil {
+			if errors.Is(err, errStreamDone) {
+				return true, nil
+			}
+			return false, err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return false, &sseReadError{err: err}
+	}
+	return false, nil
+}
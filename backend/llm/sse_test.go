@@ -0,0 +1,84 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReadSSE_Framing(t *testing.T) {
+	stream := strings.Join([]string{
+		": keep-alive",
+		"",
+		"event: message",
+		"id: 1",
+		"data: first",
+		"",
+		"",
+		":ping",
+		"data: multi",
+		"data:  line",
+		"",
+		"data: [DONE]",
+		"",
+		"data: after done",
+		"",
+	}, "\n")
+
+	var events []string
+	err := readSSE(strings.NewReader(stream), func(data string) error {
+		events = append(events, data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readSSE failed: %v", err)
+	}
+	if want := []string{"first", "multi\n line"}; fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Errorf("events = %q, want %q", events, want)
+	}
+}
+
+func TestReadSSE_DropsIncompleteEvent(t *testing.T) {
+	var events []string
+	err := readSSE(strings.NewReader("data: whole\n\ndata: {\"cut"), func(data string) error {
+		events = append(events, data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readSSE failed: %v", err)
+	}
+	if len(events) != 1 || events[0] != "whole" {
+		t.Errorf("events = %q", events)
+	}
+
+	err = readSSEUntilDone(strings.NewReader("data: whole\n\ndata: [DONE]"), func(string) error { return nil })
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("unterminated [DONE] should count as truncated, got %v", err)
+	}
+}
+
+func TestOpenAIClient_StreamKeepAlives(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": connected\n\n")
+		for _, d := range []string{"<html>", "</html>"} {
+			fmt.Fprint(w, ": keep-alive\r\n\r\n\r\n")
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\r\n\r\n", d)
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprint(w, ": bye\n\ndata: [DONE]\n\n")
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	var out strings.Builder
+	if err := client.GenerateCodeStream(context.Background(), "hello", &out); err != nil {
+		t.Fatalf("GenerateCodeStream failed: %v", err)
+	}
+	if out.String() != "<html></html>" {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
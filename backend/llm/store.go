@@ -0,0 +1,23 @@
+package llm
+
+import "maps"
+
+// WithStore sets OpenAI's store flag, keeping completions for the
+// dashboard and evals. Other providers ignore it.
+func WithStore(enabled bool) Option {
+	return func(o *options) {
+		o.store = enabled
+	}
+}
+
+// WithMetadata attaches metadata to OpenAI requests, e.g. user or project
+// IDs for dashboard filtering. OpenAI only keeps it for stored completions,
+// see WithStore. Repeated calls merge; other providers ignore it.
+func WithMetadata(metadata map[string]string) Option {
+	return func(o *options) {
+		if o.metadata == nil {
+			o.metadata = make(map[string]string, len(metadata))
+		}
+		maps.Copy(o.metadata, metadata)
+	}
+}
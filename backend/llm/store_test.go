@@ -0,0 +1,41 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestOpenAIClient_StoreAndMetadata(t *testing.T) {
+	var body map[string]any
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body = nil
+		json.NewDecoder(r.Body).Decode(&body)
+		writeChatResponse(w, "ok")
+	})
+
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL),
+		WithStore(true),
+		WithMetadata(map[string]string{"user_id": "u1"}),
+		WithMetadata(map[string]string{"project_id": "p1"}),
+	)
+	if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	metadata, _ := body["metadata"].(map[string]any)
+	if body["store"] != true || metadata["user_id"] != "u1" || metadata["project_id"] != "p1" {
+		t.Errorf("unexpected store/metadata: %v, %v", body["store"], body["metadata"])
+	}
+
+	plain := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+	if _, err := plain.GenerateCode(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	if _, ok := body["store"]; ok {
+		t.Error("store should be omitted by default")
+	}
+	if _, ok := body["metadata"]; ok {
+		t.Error("metadata should be omitted by default")
+	}
+}
@@ -0,0 +1,501 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+
+	"github.com/egedolmaci/scaffolder/backend/parser"
+)
+
+// openAIStreamChunk is one data frame. Delta frames carry choices; with
+// stream_options.include_usage the stream ends with a frame carrying only
+// usage and an empty choices list.
+type openAIStreamChunk struct {
+	Choices []openAIStreamChoice `json:"choices"`
+	Usage   *openAIUsage         `json:"usage,omitempty"`
+	Error   *openAIError         `json:"error,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIStreamChoice struct {
+	Delta        openAIStreamDelta `json:"delta"`
+	FinishReason string            `json:"finish_reason"`
+}
+
+type openAIStreamDelta struct {
+	Content   string                `json:"content"`
+	ToolCalls []openAIToolCallDelta `json:"tool_calls"`
+}
+
+// WithStreamUsage requests token usage on streaming calls and passes it to
+// fn once the stream ends, failed streams included. Usage of reconnected
+// streams is summed.
+func WithStreamUsage(fn func(Usage)) Option {
+	return func(o *options) {
+		o.streamUsage = fn
+	}
+}
+
+// GenerateCodeStream streams the model output, writing each content delta
+// to w as it arrives. To also capture the output, e.g. to a file, pass an
+// io.MultiWriter; its first failed write ends the stream. Use
+// GenerateCodeStreamMulti to keep streaming to the other writers instead.
+func (o *OpenAIClient) GenerateCodeStream(ctx context.Context, prompt string, w io.Writer) error {
+	ctx, end, err := o.life.begin(ctx)
+	if err != nil {
+		return o.opts.providerError(err)
+	}
+
+	start := o.opts.clock.Now()
+	o.opts.logRequest(ctx, o.model, prompt)
+
+	callCtx, cancel := o.opts.callContext(ctx)
+	defer cancel()
+
+	err = o.opts.providerError(end(o.stream(callCtx, prompt, w)))
+	o.opts.logStreamEnd(ctx, o.model, o.opts.clock.Now().Sub(start), err)
+
+	return err
+}
+
+// streamContinuePrompt asks the model to resume after a reconnect.
+const streamContinuePrompt = "Continue exactly where you left off. Do not repeat anything already written."
+
+func (o *OpenAIClient) stream(ctx context.Context, prompt string, w io.Writer) error {
+	request, err := o.buildRequest(ctx, prompt)
+	if err != nil {
+		return err
+	}
+	request.Stream = true
+	request.Tools = nil
+	if o.opts.streamUsage != nil {
+		request.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+	if err := o.moderate(ctx, prompt); err != nil {
+		return err
+	}
+
+	var received strings.Builder
+	out := io.MultiWriter(w, &received)
+	messages := request.Messages
+
+	// Each reconnect is billed as its own request.
+	var total Usage
+	if o.opts.streamUsage != nil {
+		defer func() { o.opts.streamUsage(total) }()
+	}
+
+	for retries, reconnects := 0, 0; ; {
+		var usage Usage
+		err = o.streamOnce(ctx, request, out, &usage)
+		total.PromptTokens += usage.PromptTokens
+		total.CompletionTokens += usage.CompletionTokens
+
+		var readErr *sseReadError
+		if err == nil || ctx.Err() != nil || !errors.As(err, &readErr) {
+			return err
+		}
+		if received.Len() == 0 {
+			if !o.opts.retryStream(ctx, request.Model, &retries, err) {
+				return err
+			}
+			continue
+		}
+
+		resumed := append(messages[:len(messages):len(messages)],
+			openAIMessage{Role: string(RoleAssistant), Content: received.String()},
+			openAIMessage{Role: string(RoleUser), Content: streamContinuePrompt},
+		)
+		if reconnects >= o.opts.streamReconnects || o.opts.checkPromptSize(request.Model, fromOpenAIMessages(resumed)) != nil {
+			return err
+		}
+		reconnects++
+		request.Messages = resumed
+
+		if o.opts.logger != nil {
+			o.opts.logger.WarnContext(ctx, "llm stream interrupted, reconnecting",
+				"model", request.Model, "attempt", reconnects, "received", received.Len(), "error", err)
+		}
+	}
+}
+
+// retryStream decides whether a stream that failed before writing anything
+// is resent, counting the retry in *retries, and waits out the backoff. A
+// stream with no output can be resent as is without duplicating text, so
+// this follows WithRetries and any RetryBudget.
+func (o *options) retryStream(ctx context.Context, model string, retries *int, err error) bool {
+	if *retries >= o.retry.maxRetries || !takeRetry(ctx) {
+		return false
+	}
+	*retries++
+
+	if o.logger != nil {
+		o.logger.WarnContext(ctx, "llm stream failed before any output, retrying",
+			"model", model, "attempt", *retries, "error", err)
+	}
+	rng := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	return sleepContext(ctx, clockOrReal(o.retry.clock), o.retry.backoff(*retries-1, rng)) == nil
+}
+
+// streamOnce runs one streaming request, writing deltas to w and recording
+// the reported usage in usage.
+func (o *OpenAIClient) streamOnce(ctx context.Context, request openAIRequest, w io.Writer, usage *Usage) error {
+	var toolCalls toolCallAccumulator
+	err := o.streamEvents(ctx, request, func(data string) error {
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+
+		if chunk.Error != nil {
+			return chunk.Error.apiError()
+		}
+
+		// Usage is cumulative for the request, so the last frame wins.
+		if chunk.Usage != nil {
+			*usage = Usage{PromptTokens: chunk.Usage.PromptTokens, CompletionTokens: chunk.Usage.CompletionTokens}
+		}
+
+		for _, choice := range chunk.Choices {
+			for _, d := range choice.Delta.ToolCalls {
+				toolCalls.add(d)
+			}
+			if choice.FinishReason == "tool_calls" {
+				return &ToolCallError{Calls: toolCalls.calls}
+			}
+			if choice.FinishReason == "content_filter" {
+				return ErrContentFiltered
+			}
+
+			if choice.Delta.Content == "" {
+				continue
+			}
+			if _, err := io.WriteString(w, choice.Delta.Content); err != nil {
+				return fmt.Errorf("failed to write stream output: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(toolCalls.calls) > 0 {
+		return &ToolCallError{Calls: toolCalls.calls}
+	}
+
+	return nil
+}
+
+// streamEvents sends a streaming request and passes the data of each event
+// to handle until the stream ends with "[DONE]".
+func (o *OpenAIClient) streamEvents(ctx context.Context, request openAIRequest, handle func(data string) error) error {
+	req, err := o.newRequest(ctx, request)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := doJSONRequest(o.httpClient, req, o.opts.retryPolicy())
+	if err != nil {
+		return fmt.Errorf("failed to call API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return newAPIError(resp.StatusCode, body)
+	}
+
+	if err := checkContentType(resp, "text/event-stream"); err != nil {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, bodySnippetLen+1))
+		return fmt.Errorf("%w: %s", err, bodySnippet(body))
+	}
+
+	err = readSSEUntilDone(resp.Body, func(data string) error {
+		// The scanner may still hold buffered events after cancellation;
+		// don't hand them on.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return handle(data)
+	})
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if errors.Is(err, ErrToolCallRequested) || errors.Is(err, ErrContentFiltered) {
+			return err
+		}
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	return nil
+}
+
+// StreamRaw streams prompt and sends the JSON data of each event on the
+// first channel untouched, for callers that need more than the text
+// deltas; "[DONE]" is not sent. The channel is closed when the stream
+// ends, after which the second channel yields the call's error, nil on
+// success. Reconnects and tool calls are left to the caller. Callers that
+// stop reading early must cancel ctx.
+func (o *OpenAIClient) StreamRaw(ctx context.Context, prompt string) (<-chan json.RawMessage, <-chan error) {
+	events := make(chan json.RawMessage)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		err := o.streamRaw(ctx, prompt, events)
+		close(events)
+		errc <- err
+	}()
+
+	return events, errc
+}
+
+func (o *OpenAIClient) streamRaw(ctx context.Context, prompt string, events chan<- json.RawMessage) error {
+	ctx, end, err := o.life.begin(ctx)
+	if err != nil {
+		return o.opts.providerError(err)
+	}
+
+	start := o.opts.clock.Now()
+	o.opts.logRequest(ctx, o.model, prompt)
+
+	callCtx, cancel := o.opts.callContext(ctx)
+	defer cancel()
+
+	err = o.opts.providerError(end(o.sendRaw(callCtx, prompt, events)))
+	o.opts.logStreamEnd(ctx, o.model, o.opts.clock.Now().Sub(start), err)
+
+	return err
+}
+
+func (o *OpenAIClient) sendRaw(ctx context.Context, prompt string, events chan<- json.RawMessage) error {
+	request, err := o.buildRequest(ctx, prompt)
+	if err != nil {
+		return err
+	}
+	request.Stream = true
+	if err := o.moderate(ctx, prompt); err != nil {
+		return err
+	}
+
+	return o.streamEvents(ctx, request, func(data string) error {
+		if !json.Valid([]byte(data)) {
+			return fmt.Errorf("failed to parse stream chunk: %s", bodySnippet([]byte(data)))
+		}
+		select {
+		case events <- json.RawMessage(data):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// Stream streams p's output to w if p is a StreamProvider. Otherwise it
+// falls back to GenerateCode and writes the whole result to w in one
+// write, so callers can use one code path for every backend. Nothing is
+// written if the fallback call fails.
+func Stream(ctx context.Context, p Provider, prompt string, w io.Writer) error {
+	if sp, ok := p.(StreamProvider); ok {
+		return sp.GenerateCodeStream(ctx, prompt, w)
+	}
+
+	code, err := p.GenerateCode(ctx, prompt)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, code); err != nil {
+		return fmt.Errorf("failed to write stream output: %w", err)
+	}
+	return nil
+}
+
+// GenerateCodeStreamPartial is GenerateCodeStream that also returns all
+// output received, including when the stream fails or ctx is canceled,
+// e.g. to keep the partial page after a "stop generating" button. err is
+// the stream's error, the context's after a cancel.
+func (o *OpenAIClient) GenerateCodeStreamPartial(ctx context.Context, prompt string, w io.Writer) (partial string, err error) {
+	return streamPartial(ctx, o, prompt, w)
+}
+
+func streamPartial(ctx context.Context, p StreamProvider, prompt string, w io.Writer) (string, error) {
+	var received strings.Builder
+	err := p.GenerateCodeStream(ctx, prompt, io.MultiWriter(w, &received))
+	return received.String(), err
+}
+
+// GenerateCodeStreamMulti streams the model output to every writer, e.g. an
+// HTTP response for live display and a file for durable capture. A writer
+// whose write fails is dropped and the others keep receiving output; the
+// stream only ends early once all have failed. The returned error is the
+// stream's, or else the writers' failures joined.
+func (o *OpenAIClient) GenerateCodeStreamMulti(ctx context.Context, prompt string, writers ...io.Writer) error {
+	return streamMulti(ctx, o, prompt, writers...)
+}
+
+func streamMulti(ctx context.Context, p StreamProvider, prompt string, writers ...io.Writer) error {
+	tee := NewTeeWriter(writers...)
+	if err := p.GenerateCodeStream(ctx, prompt, tee); err != nil {
+		return err
+	}
+	return tee.Err()
+}
+
+// TeeWriter duplicates writes to several writers like io.MultiWriter, but
+// drops a writer whose write fails rather than failing the write, so the
+// others carry on. A write only fails once every writer has failed.
+type TeeWriter struct {
+	writers []io.Writer
+	errs    []error
+}
+
+func NewTeeWriter(writers ...io.Writer) *TeeWriter {
+	return &TeeWriter{writers: append([]io.Writer(nil), writers...)}
+}
+
+func (t *TeeWriter) Write(p []byte) (int, error) {
+	live := t.writers[:0]
+	for _, w := range t.writers {
+		n, err := w.Write(p)
+		if err == nil && n < len(p) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			t.errs = append(t.errs, err)
+			continue
+		}
+		live = append(live, w)
+	}
+	t.writers = live
+
+	if len(t.writers) == 0 && len(t.errs) > 0 {
+		return 0, fmt.Errorf("all writers failed: %w", t.Err())
+	}
+	return len(p), nil
+}
+
+// Err returns the failures of the writers dropped so far, joined, or nil.
+func (t *TeeWriter) Err() error {
+	return errors.Join(t.errs...)
+}
+
+// GenerateCodeStreamExtract streams deltas on the returned channel and
+// returns a wait function that blocks until the stream completes and yields
+// the fence-stripped code. The channel is closed when the stream ends; wait
+// drains any deltas the caller didn't consume.
+func (o *OpenAIClient) GenerateCodeStreamExtract(ctx context.Context, prompt string) (<-chan string, func() (string, error)) {
+	return streamExtract(ctx, o, prompt)
+}
+
+func streamExtract(ctx context.Context, p StreamProvider, prompt string) (<-chan string, func() (string, error)) {
+	deltas := make(chan string, 16)
+	done := make(chan struct{})
+
+	var full strings.Builder
+	var streamErr error
+
+	go func() {
+		defer close(done)
+		defer close(deltas)
+
+		w := &chanWriter{ctx: ctx, ch: deltas, full: &full}
+		streamErr = p.GenerateCodeStream(ctx, prompt, w)
+	}()
+
+	wait := func() (string, error) {
+		for range deltas {
+		}
+		<-done
+
+		if streamErr != nil {
+			return "", streamErr
+		}
+
+		code, _, err := parser.ExtractCodeBlock(full.String())
+		if err != nil {
+			return "", fmt.Errorf("failed to extract code: %w", err)
+		}
+		return code, nil
+	}
+
+	return deltas, wait
+}
+
+// StreamDelta is a chunk of streamed output with a rough estimate of how
+// far along the generation is.
+type StreamDelta struct {
+	Text string
+	// Progress is the fraction of the expected output received so far, in
+	// [0, 1). It is only meaningful with WithMaxTokens, which sets the
+	// expected length; otherwise a typical page length is assumed. A model
+	// that stops early never gets near 1.
+	Progress float64
+}
+
+// expectedStreamTokens is the output length assumed for progress when no
+// max tokens are set.
+const expectedStreamTokens = 2048
+
+// GenerateCodeStreamProgress is GenerateCodeStreamExtract with a progress
+// estimate on each delta.
+func (o *OpenAIClient) GenerateCodeStreamProgress(ctx context.Context, prompt string) (<-chan StreamDelta, func() (string, error)) {
+	deltas, wait := streamExtract(ctx, o, prompt)
+
+	expected := o.opts.maxTokens
+	if expected <= 0 {
+		expected = expectedStreamTokens
+	}
+
+	out := make(chan StreamDelta, 16)
+	go func() {
+		defer close(out)
+		received := 0
+		for d := range deltas {
+			n, err := EstimateTokens(ResolveModel(o.model), d)
+			if err != nil {
+				n = estimateTokensHeuristic(d)
+			}
+			received += n
+
+			select {
+			case out <- StreamDelta{Text: d, Progress: min(float64(received)/float64(expected), 0.99)}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return out, func() (string, error) {
+		for range out {
+		}
+		return wait()
+	}
+}
+
+// chanWriter forwards each write to a channel while keeping the full text.
+type chanWriter struct {
+	ctx  context.Context
+	ch   chan<- string
+	full *strings.Builder
+}
+
+func (c *chanWriter) Write(p []byte) (int, error) {
+	c.full.Write(p)
+
+	select {
+	case c.ch <- string(p):
+		return len(p), nil
+	case <-c.ctx.Done():
+		return 0, c.ctx.Err()
+	}
+}
@@ -0,0 +1,570 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/egedolmaci/scaffolder/backend/llm/testutil"
+)
+
+func TestOpenAIClient_GenerateCodeStream(t *testing.T) {
+	server := testutil.NewSSEServer(t, testutil.SSEConfig{
+		Chunks: testutil.OpenAIDeltas("<html>", "<body>hi</body>", "</html>"),
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	var out strings.Builder
+	if err := client.GenerateCodeStream(context.Background(), "hello", &out); err != nil {
+		t.Fatalf("GenerateCodeStream failed: %v", err)
+	}
+	if out.String() != "<html><body>hi</body></html>" {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
+
+func TestOpenAIClient_GenerateCodeStreamExtract(t *testing.T) {
+	server := testutil.NewSSEServer(t, testutil.SSEConfig{
+		Chunks: testutil.OpenAIDeltas("```html\n", "<html></html>", "\n```"),
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	deltas, wait := client.GenerateCodeStreamExtract(context.Background(), "hello")
+
+	var count int
+	for range deltas {
+		count++
+	}
+
+	code, err := wait()
+	if err != nil {
+		t.Fatalf("wait failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 deltas, got %d", count)
+	}
+	if code != "<html></html>" {
+		t.Errorf("unexpected code: %q", code)
+	}
+}
+
+func TestOpenAIClient_GenerateCodeStreamExtractWithoutDraining(t *testing.T) {
+	deltas := make([]string, 0, 64)
+	deltas = append(deltas, "```html\n")
+	for i := 0; i < 60; i++ {
+		deltas = append(deltas, "<p></p>")
+	}
+	deltas = append(deltas, "\n```")
+	server := testutil.NewSSEServer(t, testutil.SSEConfig{Chunks: testutil.OpenAIDeltas(deltas...)})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	_, wait := client.GenerateCodeStreamExtract(context.Background(), "hello")
+
+	code, err := wait()
+	if err != nil {
+		t.Fatalf("wait failed: %v", err)
+	}
+	if code != strings.Repeat("<p></p>", 60) {
+		t.Errorf("unexpected code: %q", code)
+	}
+}
+
+func TestOpenAIClient_GenerateCodeStreamProgress(t *testing.T) {
+	chunks := []string{"```html\n"}
+	for range 20 {
+		chunks = append(chunks, "<p></p>")
+	}
+	chunks = append(chunks, "\n```")
+	server := testutil.NewSSEServer(t, testutil.SSEConfig{Chunks: testutil.OpenAIDeltas(chunks...)})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithMaxTokens(40))
+
+	deltas, wait := client.GenerateCodeStreamProgress(context.Background(), "hello")
+
+	var progress []float64
+	for d := range deltas {
+		progress = append(progress, d.Progress)
+	}
+	if _, err := wait(); err != nil {
+		t.Fatalf("wait failed: %v", err)
+	}
+
+	if len(progress) != len(chunks) {
+		t.Fatalf("expected %d deltas, got %d", len(chunks), len(progress))
+	}
+	for i := 1; i < len(progress); i++ {
+		if progress[i] < progress[i-1] {
+			t.Fatalf("progress went backwards: %v", progress)
+		}
+	}
+	if progress[0] <= 0 || progress[len(progress)-1] != 0.99 {
+		t.Errorf("unexpected progress: %v", progress)
+	}
+}
+
+// slowSSEServer emits a delta every interval until the client goes away.
+func slowSSEServer(t *testing.T, interval time.Duration) *testutil.SSEServer {
+	return testutil.NewSSEServer(t, testutil.SSEConfig{
+		Chunks: testutil.OpenAIDeltas("x"),
+		Delay:  interval,
+		Loop:   true,
+	})
+}
+
+// waitForGoroutines fails the test if the goroutine count doesn't drop back
+// to baseline shortly.
+func waitForGoroutines(t *testing.T, baseline int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+		n := runtime.NumGoroutine()
+		if n <= baseline {
+			return
+		}
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("goroutine leak: %d running, baseline %d\n%s", n, baseline, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// cancelAfterWriter cancels the context after n writes and counts writes
+// that arrive afterwards.
+type cancelAfterWriter struct {
+	n           int
+	cancel      context.CancelFunc
+	writes      int
+	afterCancel int
+}
+
+func (c *cancelAfterWriter) Write(p []byte) (int, error) {
+	c.writes++
+	if c.writes > c.n {
+		c.afterCancel++
+	}
+	if c.writes == c.n {
+		c.cancel()
+	}
+	return len(p), nil
+}
+
+func TestOpenAIClient_GenerateCodeStreamCancelNoLeak(t *testing.T) {
+	server := slowSSEServer(t, 5*time.Millisecond)
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+	baseline := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := &cancelAfterWriter{n: 3, cancel: cancel}
+
+	start := time.Now()
+	err := client.GenerateCodeStream(ctx, "hello", w)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("stream took %v to stop after cancel", elapsed)
+	}
+	if w.afterCancel != 0 {
+		t.Errorf("writer received %d writes after cancel", w.afterCancel)
+	}
+	select {
+	case <-server.Disconnected():
+	case <-time.After(time.Second):
+		t.Error("server did not see the client disconnect")
+	}
+
+	waitForGoroutines(t, baseline)
+}
+
+func TestOpenAIClient_GenerateCodeStreamExtractCancelNoLeak(t *testing.T) {
+	server := slowSSEServer(t, 5*time.Millisecond)
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+	baseline := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	deltas, wait := client.GenerateCodeStreamExtract(ctx, "hello")
+
+	// Read a few deltas, then abandon the channel and cancel.
+	for i := 0; i < 3; i++ {
+		<-deltas
+	}
+	cancel()
+
+	if _, err := wait(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	waitForGoroutines(t, baseline)
+}
+
+func TestOpenAIClient_GenerateCodeStreamPreview(t *testing.T) {
+	server := testutil.NewSSEServer(t, testutil.SSEConfig{
+		Chunks: testutil.OpenAIDeltas("```html\n<html><head>", "</head><body><h1>Hi</h1>", "</body>", "</html>\n```"),
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	var out strings.Builder
+	var previews []string
+	err := client.GenerateCodeStreamPreview(context.Background(), "hello", &out, func(html string) {
+		previews = append(previews, html)
+	})
+	if err != nil {
+		t.Fatalf("GenerateCodeStreamPreview failed: %v", err)
+	}
+
+	if len(previews) != 1 {
+		t.Fatalf("expected 1 preview signal, got %d", len(previews))
+	}
+	if previews[0] != "<html><head></head><body><h1>Hi</h1></body>" {
+		t.Errorf("unexpected preview: %q", previews[0])
+	}
+	if !strings.HasSuffix(out.String(), "</html>\n```") {
+		t.Errorf("stream output not passed through: %q", out.String())
+	}
+}
+
+func TestOpenAIClient_StreamReconnect(t *testing.T) {
+	var calls int
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var req openAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		if calls == 1 {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"<html>\"}}]}\n\n")
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+
+		n := len(req.Messages)
+		if n < 2 || req.Messages[n-2].Role != "assistant" || req.Messages[n-2].Content != "<html>" || req.Messages[n-1].Content != streamContinuePrompt {
+			t.Errorf("reconnect request missing prefill: %+v", req.Messages)
+		}
+		testutil.WriteSSE(w, testutil.OpenAIDeltas("<body></body>", "</html>")...)
+	})
+
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithStreamReconnect(1))
+
+	var out strings.Builder
+	if err := client.GenerateCodeStream(context.Background(), "hello", &out); err != nil {
+		t.Fatalf("GenerateCodeStream failed: %v", err)
+	}
+	if out.String() != "<html><body></body></html>" || calls != 2 {
+		t.Errorf("output=%q calls=%d", out.String(), calls)
+	}
+}
+
+func TestOpenAIClient_StreamRetry(t *testing.T) {
+	t.Run("before any output", func(t *testing.T) {
+		var calls int
+		server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			var req openAIRequest
+			json.NewDecoder(r.Body).Decode(&req)
+
+			w.Header().Set("Content-Type", "text/event-stream")
+			if calls == 1 {
+				fmt.Fprint(w, ": keep-alive\n\n")
+				w.(http.Flusher).Flush()
+				panic(http.ErrAbortHandler)
+			}
+			if len(req.Messages) != 2 {
+				t.Errorf("retry should resend the original request, got %+v", req.Messages)
+			}
+			testutil.WriteSSE(w, testutil.OpenAIDeltas("<html>", "</html>")...)
+		})
+		client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithRetries(1), WithBackoff(time.Millisecond, time.Millisecond))
+
+		var out strings.Builder
+		if err := client.GenerateCodeStream(context.Background(), "hello", &out); err != nil {
+			t.Fatalf("GenerateCodeStream failed: %v", err)
+		}
+		if out.String() != "<html></html>" || calls != 2 {
+			t.Errorf("output=%q calls=%d", out.String(), calls)
+		}
+	})
+
+	t.Run("mid-stream", func(t *testing.T) {
+		var calls int
+		server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"<html>\"}}]}\n\n")
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		})
+		client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithRetries(3), WithBackoff(time.Millisecond, time.Millisecond))
+
+		partial, err := client.GenerateCodeStreamPartial(context.Background(), "hello", io.Discard)
+		if err == nil {
+			t.Fatal("expected the stream error")
+		}
+		if partial != "<html>" || calls != 1 {
+			t.Errorf("partial=%q calls=%d, want the partial output and no restart", partial, calls)
+		}
+	})
+}
+
+func TestOpenAIClient_StreamUsageFrame(t *testing.T) {
+	recorded, err := os.ReadFile("testdata/openai_stream_usage.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req openAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.StreamOptions == nil || !req.StreamOptions.IncludeUsage {
+			t.Errorf("stream_options.include_usage not set: %+v", req.StreamOptions)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write(recorded)
+	})
+
+	var usage Usage
+	var calls int
+	client := NewOpenAIClient("test-key", "gpt-4o", WithBaseURL(server.URL), WithStreamUsage(func(u Usage) {
+		calls++
+		usage = u
+	}))
+
+	var out strings.Builder
+	if err := client.GenerateCodeStream(context.Background(), "hello", &out); err != nil {
+		t.Fatalf("GenerateCodeStream failed: %v", err)
+	}
+	if out.String() != "<html></html>" {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+	if calls != 1 || usage != (Usage{PromptTokens: 12, CompletionTokens: 4}) {
+		t.Errorf("calls=%d usage=%+v", calls, usage)
+	}
+}
+
+func TestOpenAIClient_StreamWithoutReconnectFails(t *testing.T) {
+	server := testutil.NewSSEServer(t, testutil.SSEConfig{
+		Chunks:    testutil.OpenAIDeltas("<html>", "</html>"),
+		FailAfter: 1,
+	})
+
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	var out strings.Builder
+	if err := client.GenerateCodeStream(context.Background(), "hello", &out); err == nil {
+		t.Fatal("expected error from dropped stream")
+	}
+	if out.String() != "<html>" {
+		t.Errorf("expected output up to the drop, got %q", out.String())
+	}
+}
+
+func TestOpenAIClient_StreamMissingDone(t *testing.T) {
+	server := testutil.NewSSEServer(t, testutil.SSEConfig{
+		Chunks:   testutil.OpenAIDeltas("<html>"),
+		OmitDone: true,
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	var out strings.Builder
+	err := client.GenerateCodeStream(context.Background(), "hello", &out)
+	var readErr *sseReadError
+	if !errors.As(err, &readErr) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected a truncated stream error, got %v", err)
+	}
+}
+
+func TestOpenAIClient_StreamMissingDoneReconnects(t *testing.T) {
+	var calls int
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "text/event-stream")
+		if calls == 1 {
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"<html>\"}}]}\n\n")
+			return
+		}
+		testutil.WriteSSE(w, testutil.OpenAIDeltas("</html>")...)
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithStreamReconnect(1))
+
+	var out strings.Builder
+	if err := client.GenerateCodeStream(context.Background(), "hello", &out); err != nil {
+		t.Fatalf("GenerateCodeStream failed: %v", err)
+	}
+	if out.String() != "<html></html>" || calls != 2 {
+		t.Errorf("output=%q calls=%d", out.String(), calls)
+	}
+}
+
+func TestOpenAIClient_StreamRaw(t *testing.T) {
+	chunks := []string{
+		`{"id":"c1","choices":[{"delta":{"content":"<html>"},"logprobs":{"content":[]}}]}`,
+		`{"id":"c1","choices":[],"usage":{"prompt_tokens":3,"completion_tokens":1}}`,
+	}
+	server := testutil.NewSSEServer(t, testutil.SSEConfig{Chunks: chunks})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	events, errc := client.StreamRaw(context.Background(), "hello")
+	var got []string
+	for ev := range events {
+		got = append(got, string(ev))
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("StreamRaw failed: %v", err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(chunks) {
+		t.Errorf("events = %q, want %q", got, chunks)
+	}
+}
+
+func TestOpenAIClient_StreamRawError(t *testing.T) {
+	server := testutil.NewSSEServer(t, testutil.SSEConfig{Chunks: []string{`{"choices":[]}`, `not json`}})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	events, errc := client.StreamRaw(context.Background(), "hello")
+	var n int
+	for range events {
+		n++
+	}
+	if err := <-errc; err == nil || !strings.Contains(err.Error(), "failed to parse stream chunk") {
+		t.Fatalf("expected a parse error, got %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 event before the error, got %d", n)
+	}
+}
+
+func TestOpenAIClient_GenerateCodeStreamPartial(t *testing.T) {
+	server := testutil.NewSSEServer(t, testutil.SSEConfig{
+		Chunks: testutil.OpenAIDeltas("<p>tick</p>"),
+		Delay:  5 * time.Millisecond,
+		Loop:   true,
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := &cancelAfterWriter{n: 3, cancel: cancel}
+
+	partial, err := client.GenerateCodeStreamPartial(ctx, "hello", w)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if partial != strings.Repeat("<p>tick</p>", 3) {
+		t.Errorf("partial = %q", partial)
+	}
+}
+
+// failingWriter fails every write after the first n.
+type failingWriter struct {
+	n   int
+	err error
+}
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	if f.n <= 0 {
+		return 0, f.err
+	}
+	f.n--
+	return len(p), nil
+}
+
+func TestOpenAIClient_StreamToMultiWriter(t *testing.T) {
+	server := testutil.NewSSEServer(t, testutil.SSEConfig{Chunks: testutil.OpenAIDeltas("<html>", "</html>")})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	var live, capture strings.Builder
+	if err := client.GenerateCodeStream(context.Background(), "hello", io.MultiWriter(&live, &capture)); err != nil {
+		t.Fatalf("GenerateCodeStream failed: %v", err)
+	}
+	if live.String() != "<html></html>" || capture.String() != live.String() {
+		t.Errorf("live = %q, capture = %q", live.String(), capture.String())
+	}
+}
+
+func TestOpenAIClient_GenerateCodeStreamMulti(t *testing.T) {
+	server := testutil.NewSSEServer(t, testutil.SSEConfig{Chunks: testutil.OpenAIDeltas("<html>", "<body>", "</html>")})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	t.Run("one writer fails", func(t *testing.T) {
+		disconnected := errors.New("client went away")
+		var capture strings.Builder
+		err := client.GenerateCodeStreamMulti(context.Background(), "hello", &failingWriter{n: 1, err: disconnected}, &capture)
+		if !errors.Is(err, disconnected) {
+			t.Fatalf("expected the writer's error, got %v", err)
+		}
+		if capture.String() != "<html><body></html>" {
+			t.Errorf("capture = %q", capture.String())
+		}
+	})
+
+	t.Run("all writers fail", func(t *testing.T) {
+		disconnected := errors.New("client went away")
+		err := client.GenerateCodeStreamMulti(context.Background(), "hello", &failingWriter{err: disconnected}, &failingWriter{n: 1, err: io.ErrClosedPipe})
+		if !errors.Is(err, disconnected) || !errors.Is(err, io.ErrClosedPipe) {
+			t.Fatalf("expected both writer errors, got %v", err)
+		}
+	})
+}
+
+// countingWriter counts writes and keeps what was written.
+type countingWriter struct {
+	buf    strings.Builder
+	writes int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.writes++
+	return c.buf.Write(p)
+}
+
+func (c *countingWriter) String() string { return c.buf.String() }
+
+func TestStream(t *testing.T) {
+	t.Run("streaming provider", func(t *testing.T) {
+		server := testutil.NewSSEServer(t, testutil.SSEConfig{Chunks: testutil.OpenAIDeltas("<html>", "</html>")})
+		client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+		var w countingWriter
+		if err := Stream(context.Background(), client, "hello", &w); err != nil {
+			t.Fatalf("Stream failed: %v", err)
+		}
+		if w.String() != "<html></html>" || w.writes != 2 {
+			t.Errorf("got %q in %d writes", w.String(), w.writes)
+		}
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		var w countingWriter
+		if err := Stream(context.Background(), &peakProvider{}, "<html></html>", &w); err != nil {
+			t.Fatalf("Stream failed: %v", err)
+		}
+		if w.String() != "<html></html>" || w.writes != 1 {
+			t.Errorf("got %q in %d writes", w.String(), w.writes)
+		}
+	})
+
+	t.Run("fallback error", func(t *testing.T) {
+		var w countingWriter
+		if err := Stream(context.Background(), failingProvider{}, "hello", &w); err == nil {
+			t.Fatal("expected the provider's error")
+		}
+		if w.writes != 0 {
+			t.Errorf("expected nothing written, got %q", w.String())
+		}
+	})
+}
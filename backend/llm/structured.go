@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var ErrStructuredOutputUnsupported = errors.New("structured outputs not supported")
+
+const filesSystemPrompt = `You are a code generator that creates multi-file web projects.
+
+Rules:
+- Generate complete, working files with modern JavaScript (ES6+) and good CSS styling
+- Respond only with JSON matching the provided schema, no additional text`
+
+type openAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type openAIJSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+	Strict bool            `json:"strict"`
+}
+
+// GenerateFilesSchema asks the model for JSON conforming to schema using
+// OpenAI Structured Outputs in strict mode, so the API enforces the schema,
+// and returns it. Models or endpoints that reject response_format fail
+// with ErrStructuredOutputUnsupported.
+func (o *OpenAIClient) GenerateFilesSchema(ctx context.Context, prompt string, schema json.RawMessage) (json.RawMessage, error) {
+	if !json.Valid(schema) {
+		return nil, errors.New("schema is not valid JSON")
+	}
+	ctx, end, err := o.life.begin(ctx)
+	if err != nil {
+		return nil, o.opts.providerError(err)
+	}
+
+	callCtx, cancel := o.opts.callContext(ctx)
+	defer cancel()
+
+	start := o.opts.clock.Now()
+	o.opts.logRequest(ctx, o.model, prompt)
+
+	out, err := o.sendSchema(callCtx, prompt, schema)
+	err = o.opts.providerError(end(err))
+	o.opts.logResponse(ctx, o.model, string(out), o.opts.clock.Now().Sub(start), err)
+
+	return out, err
+}
+
+func (o *OpenAIClient) sendSchema(ctx context.Context, prompt string, schema json.RawMessage) (json.RawMessage, error) {
+	messages, err := o.opts.buildMessagesWith(ctx, filesSystemPrompt, prompt)
+	if err != nil {
+		return nil, err
+	}
+	model := o.opts.routeModel(o.model, prompt)
+	recordModel(ctx, model)
+	request, err := o.buildChatRequest(model, messages)
+	if err != nil {
+		return nil, err
+	}
+	request.ResponseFormat = &openAIResponseFormat{
+		Type:       "json_schema",
+		JSONSchema: &openAIJSONSchema{Name: "files", Schema: schema, Strict: true},
+	}
+	if err := o.moderate(ctx, prompt); err != nil {
+		return nil, err
+	}
+
+	body, _, err := o.postFallback(ctx, &request)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest && mentionsResponseFormat(apiErr.Body) {
+		return nil, fmt.Errorf("%w by %s: %s", ErrStructuredOutputUnsupported, request.Model, apiErr.Message)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := parseOpenAIResponse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	out := json.RawMessage(strings.TrimSpace(result.Code))
+	if !json.Valid(out) {
+		return nil, fmt.Errorf("model returned invalid JSON: %s", bodySnippet(out))
+	}
+	return out, nil
+}
+
+func mentionsResponseFormat(body string) bool {
+	return strings.Contains(body, "response_format") || strings.Contains(body, "json_schema")
+}
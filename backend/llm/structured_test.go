@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+const filesSchema = `{"type":"object","properties":{"files":{"type":"array","items":{"type":"object","properties":{"path":{"type":"string"},"content":{"type":"string"}},"required":["path","content"],"additionalProperties":false}}},"required":["files"],"additionalProperties":false}`
+
+func TestOpenAIClient_GenerateFilesSchema(t *testing.T) {
+	want := `{"files":[{"path":"index.html","content":"<html></html>"}]}`
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ResponseFormat struct {
+				Type       string `json:"type"`
+				JSONSchema struct {
+					Name   string          `json:"name"`
+					Schema json.RawMessage `json:"schema"`
+					Strict bool            `json:"strict"`
+				} `json:"json_schema"`
+			} `json:"response_format"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			return
+		}
+		if req.ResponseFormat.Type != "json_schema" || !req.ResponseFormat.JSONSchema.Strict {
+			t.Errorf("unexpected response_format: %+v", req.ResponseFormat)
+		}
+		if string(req.ResponseFormat.JSONSchema.Schema) != filesSchema {
+			t.Errorf("schema not forwarded: %s", req.ResponseFormat.JSONSchema.Schema)
+		}
+		writeChatResponse(w, want)
+	})
+	client := NewOpenAIClient("test-key", "gpt-4o", WithBaseURL(server.URL))
+
+	got, err := client.GenerateFilesSchema(context.Background(), "a todo app", json.RawMessage(filesSchema))
+	if err != nil {
+		t.Fatalf("GenerateFilesSchema failed: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestOpenAIClient_GenerateFilesSchemaUnsupported(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"message":"Invalid parameter: 'response_format' of type 'json_schema' is not supported with this model."}}`))
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	_, err := client.GenerateFilesSchema(context.Background(), "a todo app", json.RawMessage(filesSchema))
+	if !errors.Is(err, ErrStructuredOutputUnsupported) {
+		t.Fatalf("expected ErrStructuredOutputUnsupported, got %v", err)
+	}
+}
+
+func TestOpenAIClient_GenerateFilesSchemaInvalidJSON(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeChatResponse(w, "```html\n<html></html>\n```")
+	})
+	client := NewOpenAIClient("test-key", "gpt-4o", WithBaseURL(server.URL))
+
+	if _, err := client.GenerateFilesSchema(context.Background(), "a todo app", json.RawMessage(filesSchema)); err == nil {
+		t.Fatal("expected error for non-JSON output")
+	}
+	if _, err := client.GenerateFilesSchema(context.Background(), "a todo app", json.RawMessage(`{`)); err == nil {
+		t.Fatal("expected error for invalid schema")
+	}
+}
+
+func TestOpenAIClient_Refusal(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":null,"refusal":"I can't help with that."}}]}`))
+	})
+	client := NewOpenAIClient("test-key", "gpt-4o", WithBaseURL(server.URL))
+
+	if _, err := client.GenerateCode(context.Background(), "hello"); !errors.Is(err, ErrModelRefused) {
+		t.Fatalf("expected ErrModelRefused, got %v", err)
+	}
+}
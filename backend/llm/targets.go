@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Built-in generation targets.
+const (
+	TargetWebApp          = "single-file-web-app"
+	TargetReactComponent  = "react-component"
+	TargetPythonCLI       = "python-cli"
+	TargetSVGIllustration = "svg-illustration"
+)
+
+// TargetPreset is a tuned system prompt and recommended parameters for one
+// kind of output. Zero parameters keep the client defaults.
+type TargetPreset struct {
+	SystemPrompt string
+	Temperature  *float64
+	MaxTokens    int
+}
+
+func (p TargetPreset) options() []Option {
+	opts := []Option{WithSystemPrompt(p.SystemPrompt)}
+	if p.Temperature != nil {
+		opts = append(opts, WithTemperature(*p.Temperature))
+	}
+	if p.MaxTokens > 0 {
+		opts = append(opts, WithMaxTokens(p.MaxTokens))
+	}
+	return opts
+}
+
+func temperature(t float64) *float64 { return &t }
+
+var (
+	targetsMu     sync.RWMutex
+	targetPresets = map[string]TargetPreset{
+		TargetWebApp: {SystemPrompt: systemPrompt},
+		TargetReactComponent: {
+			SystemPrompt: `You are a code generator that creates React components.
+
+Rules:
+- Write a single self-contained function component in modern TypeScript (TSX) using hooks
+- Export the component as the default export
+- Style with plain CSS-in-JS objects or Tailwind class names, no external component libraries
+- Type every prop with an interface
+- Do not include explanations, only code
+
+You MUST format your response as a single markdown code block tagged tsx.`,
+			Temperature: temperature(0.3),
+		},
+		TargetPythonCLI: {
+			SystemPrompt: `You are a code generator that creates Python command-line tools.
+
+Rules:
+- Write a single Python 3 script using only the standard library
+- Parse arguments with argparse and include --help text for every option
+- Put the entry point in main() behind an if __name__ == "__main__" guard
+- Exit with a non-zero status and a clear message on errors
+- Do not include explanations, only code
+
+You MUST format your response as a single markdown code block tagged python.`,
+			Temperature: temperature(0.2),
+		},
+		TargetSVGIllustration: {
+			SystemPrompt: `You are an illustrator that draws with SVG.
+
+Rules:
+- Produce one standalone SVG document with a viewBox and no fixed width or height
+- Use only inline shapes, paths and gradients; no external images, fonts or scripts
+- Group related shapes and give groups descriptive ids
+- Include a <title> describing the image
+- Do not include explanations, only code
+
+You MUST format your response as a single markdown code block tagged svg.`,
+			Temperature: temperature(0.8),
+		},
+	}
+)
+
+// RegisterTargetPreset adds or replaces the preset for target.
+func RegisterTargetPreset(target string, preset TargetPreset) {
+	targetsMu.Lock()
+	defer targetsMu.Unlock()
+	targetPresets[target] = preset
+}
+
+// LookupTargetPreset returns the preset registered for target.
+func LookupTargetPreset(target string) (TargetPreset, error) {
+	targetsMu.RLock()
+	defer targetsMu.RUnlock()
+
+	preset, ok := targetPresets[target]
+	if !ok {
+		names := make([]string, 0, len(targetPresets))
+		for name := range targetPresets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return TargetPreset{}, fmt.Errorf("unknown target %q (known: %s)", target, strings.Join(names, ", "))
+	}
+	return preset, nil
+}
+
+// NewOpenAIClientForTarget is NewOpenAIClient configured with the preset
+// for target. opts are applied after the preset and override it.
+func NewOpenAIClientForTarget(apiKey, model, target string, opts ...Option) (*OpenAIClient, error) {
+	preset, err := LookupTargetPreset(target)
+	if err != nil {
+		return nil, err
+	}
+	return NewOpenAIClient(apiKey, model, append(preset.options(), opts...)...), nil
+}
@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNewOpenAIClientForTarget(t *testing.T) {
+	var req openAIRequest
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&req)
+		writeChatResponse(w, "```python\nprint('hi')\n```")
+	})
+
+	client, err := NewOpenAIClientForTarget("test-key", "gpt-4o", TargetPythonCLI, WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("NewOpenAIClientForTarget failed: %v", err)
+	}
+	if _, err := client.GenerateCode(context.Background(), "a csv deduper"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+
+	if !strings.Contains(req.Messages[0].Content, "argparse") {
+		t.Errorf("python preset system prompt not used: %q", req.Messages[0].Content)
+	}
+	if req.Temperature == nil || *req.Temperature != 0.2 {
+		t.Errorf("preset temperature not applied: %v", req.Temperature)
+	}
+}
+
+func TestNewOpenAIClientForTarget_OptionsOverridePreset(t *testing.T) {
+	client, err := NewOpenAIClientForTarget("test-key", "gpt-4o", TargetSVGIllustration, WithTemperature(0))
+	if err != nil {
+		t.Fatalf("NewOpenAIClientForTarget failed: %v", err)
+	}
+	if *client.opts.temperature != 0 {
+		t.Errorf("temperature = %v, want 0", *client.opts.temperature)
+	}
+}
+
+func TestNewOpenAIClientForTarget_Unknown(t *testing.T) {
+	_, err := NewOpenAIClientForTarget("test-key", "gpt-4o", "cobol-mainframe")
+	if err == nil || !strings.Contains(err.Error(), `unknown target "cobol-mainframe"`) {
+		t.Fatalf("expected unknown target error, got %v", err)
+	}
+}
+
+func TestDefaultTargetMatchesDefaultPrompt(t *testing.T) {
+	preset, err := LookupTargetPreset(TargetWebApp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preset.SystemPrompt != systemPrompt {
+		t.Error("web app preset should be the default system prompt")
+	}
+}
@@ -0,0 +1,119 @@
+// Package testutil provides offline test doubles for the llm providers.
+package testutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// SSEConfig scripts the event stream an SSEServer sends.
+type SSEConfig struct {
+	// Chunks are the data payloads, one event each.
+	Chunks []string
+	// Delay is waited before each chunk.
+	Delay time.Duration
+	// Loop resends Chunks until the client goes away instead of ending
+	// the stream.
+	Loop bool
+	// FailAfter, when positive, aborts the connection after that many
+	// chunks to simulate a dropped stream.
+	FailAfter int
+	// OmitDone leaves out the final "data: [DONE]" event.
+	OmitDone bool
+}
+
+// SSEServer is an httptest.Server answering every request with the
+// scripted stream. It is closed when the test ends.
+type SSEServer struct {
+	*httptest.Server
+
+	cfg          SSEConfig
+	requests     atomic.Int32
+	disconnected chan struct{}
+	once         sync.Once
+}
+
+func NewSSEServer(t testing.TB, cfg SSEConfig) *SSEServer {
+	t.Helper()
+
+	s := &SSEServer{cfg: cfg, disconnected: make(chan struct{})}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Requests returns how many requests the server has received.
+func (s *SSEServer) Requests() int {
+	return int(s.requests.Load())
+}
+
+// Disconnected is closed once a client goes away before its stream ended.
+func (s *SSEServer) Disconnected() <-chan struct{} {
+	return s.disconnected
+}
+
+func (s *SSEServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.requests.Add(1)
+	// The server only notices a client disconnect once the body is read.
+	io.Copy(io.Discard, r.Body)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher := w.(http.Flusher)
+
+	sent := 0
+	for {
+		for _, chunk := range s.cfg.Chunks {
+			if s.cfg.FailAfter > 0 && sent == s.cfg.FailAfter {
+				panic(http.ErrAbortHandler)
+			}
+
+			select {
+			case <-r.Context().Done():
+				s.once.Do(func() { close(s.disconnected) })
+				return
+			case <-time.After(s.cfg.Delay):
+			}
+
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+			flusher.Flush()
+			sent++
+		}
+
+		if !s.cfg.Loop || len(s.cfg.Chunks) == 0 {
+			break
+		}
+	}
+
+	if !s.cfg.OmitDone {
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}
+}
+
+// WriteSSE writes chunks as one complete event stream ending with
+// "data: [DONE]", for handlers that script a stream per request.
+func WriteSSE(w http.ResponseWriter, chunks ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, chunk := range chunks {
+		fmt.Fprintf(w, "data: %s\n\n", chunk)
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// OpenAIDeltas encodes each delta as an OpenAI chat completion chunk.
+func OpenAIDeltas(deltas ...string) []string {
+	chunks := make([]string, len(deltas))
+	for i, d := range deltas {
+		data, _ := json.Marshal(map[string]any{
+			"choices": []any{map[string]any{"delta": map[string]string{"content": d}}},
+		})
+		chunks[i] = string(data)
+	}
+	return chunks
+}
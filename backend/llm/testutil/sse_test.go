@@ -0,0 +1,60 @@
+package testutil
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSSEServer(t *testing.T) {
+	server := NewSSEServer(t, SSEConfig{Chunks: []string{`{"a":1}`, `{"b":2}`}})
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	want := "data: {\"a\":1}\n\ndata: {\"b\":2}\n\ndata: [DONE]\n\n"
+	if string(body) != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if server.Requests() != 1 {
+		t.Errorf("Requests() = %d, want 1", server.Requests())
+	}
+}
+
+func TestWriteSSE(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteSSE(rec, `{"a":1}`)
+
+	if want := "data: {\"a\":1}\n\ndata: [DONE]\n\n"; rec.Body.String() != want {
+		t.Errorf("body = %q, want %q", rec.Body.String(), want)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
+
+func TestSSEServer_FailAfter(t *testing.T) {
+	server := NewSSEServer(t, SSEConfig{Chunks: []string{"1", "2", "3"}, FailAfter: 2})
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Error("expected read error from aborted stream")
+	}
+	if string(body) != "data: 1\n\ndata: 2\n\n" {
+		t.Errorf("body = %q", body)
+	}
+}
@@ -0,0 +1,41 @@
+package llm
+
+import "fmt"
+
+// WithThinking enables Anthropic's extended thinking with a budget of
+// budgetTokens, which must be at least 1024 and below max_tokens. The
+// thinking is returned in separate blocks that never end up in the
+// generated code; WithReturnThinking exposes it. Temperature isn't
+// supported with thinking and is left out of the request. Other providers
+// ignore this option.
+func WithThinking(budgetTokens int) Option {
+	return func(o *options) {
+		o.thinkingBudget = budgetTokens
+	}
+}
+
+// WithReturnThinking puts the text of Anthropic thinking blocks in
+// GenerationResult.Reasoning instead of discarding it. Streams never write
+// thinking to the writer either way.
+func WithReturnThinking(enabled bool) Option {
+	return func(o *options) {
+		o.returnThinking = enabled
+	}
+}
+
+type anthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+// anthropicThinkingConfig returns the thinking parameter for a request
+// with maxTokens, or nil if thinking is off.
+func (o *options) anthropicThinkingConfig(maxTokens int) (*anthropicThinking, error) {
+	if o.thinkingBudget <= 0 {
+		return nil, nil
+	}
+	if o.thinkingBudget >= maxTokens {
+		return nil, fmt.Errorf("thinking budget %d must be below max_tokens %d", o.thinkingBudget, maxTokens)
+	}
+	return &anthropicThinking{Type: "enabled", BudgetTokens: o.thinkingBudget}, nil
+}
@@ -0,0 +1,84 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAnthropicClient_Thinking(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req anthropicRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Thinking == nil || req.Thinking.Type != "enabled" || req.Thinking.BudgetTokens != 2048 {
+			t.Errorf("thinking not requested: %+v", req.Thinking)
+		}
+		if req.Temperature != nil {
+			t.Errorf("temperature sent with thinking")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(anthropicResponse{
+			Content: []anthropicContentBlock{
+				{Type: "thinking", Thinking: "The user wants a todo page."},
+				{Type: "text", Text: "<html></html>"},
+			},
+			StopReason: "end_turn",
+		})
+	})
+
+	for _, returnThinking := range []bool{false, true} {
+		client := NewAnthropicClient("test-key", "",
+			WithBaseURL(server.URL),
+			WithTemperature(0.5),
+			WithThinking(2048),
+			WithReturnThinking(returnThinking),
+		)
+
+		result, err := client.Generate(context.Background(), "hello")
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if result.Code != "<html></html>" {
+			t.Errorf("thinking leaked into the code: %q", result.Code)
+		}
+		want := ""
+		if returnThinking {
+			want = "The user wants a todo page."
+		}
+		if result.Reasoning != want {
+			t.Errorf("ReturnThinking=%v: Reasoning = %q, want %q", returnThinking, result.Reasoning, want)
+		}
+	}
+}
+
+func TestAnthropicClient_ThinkingBudgetTooLarge(t *testing.T) {
+	client := NewAnthropicClient("test-key", "", WithBaseURL("http://unused.invalid"), WithThinking(defaultAnthropicMaxTokens))
+	if _, err := client.GenerateCode(context.Background(), "hello"); err == nil || !strings.Contains(err.Error(), "thinking budget") {
+		t.Errorf("expected a thinking budget error, got %v", err)
+	}
+}
+
+func TestAnthropicClient_StreamSkipsThinking(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, data := range []string{
+			`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Planning the page."}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"<html></html>"}}`,
+			`{"type":"message_stop"}`,
+		} {
+			w.Write([]byte("data: " + data + "\n\n"))
+		}
+	})
+	client := NewAnthropicClient("test-key", "", WithBaseURL(server.URL), WithThinking(2048))
+
+	var out strings.Builder
+	if err := client.GenerateCodeStream(context.Background(), "hello", &out); err != nil {
+		t.Fatalf("GenerateCodeStream failed: %v", err)
+	}
+	if out.String() != "<html></html>" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
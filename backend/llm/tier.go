@@ -0,0 +1,30 @@
+package llm
+
+import "fmt"
+
+// ServiceTier is OpenAI's processing tier, trading latency for cost.
+type ServiceTier string
+
+const (
+	ServiceTierAuto     ServiceTier = "auto"
+	ServiceTierDefault  ServiceTier = "default"
+	ServiceTierFlex     ServiceTier = "flex"
+	ServiceTierPriority ServiceTier = "priority"
+)
+
+// WithServiceTier sets OpenAI's service_tier, e.g. ServiceTierFlex for
+// cheaper, slower batch work. An unknown tier fails every request. The tier
+// that served a call is reported in GenerationResult.ServiceTier.
+func WithServiceTier(tier ServiceTier) Option {
+	return func(o *options) {
+		o.serviceTier = tier
+	}
+}
+
+func (o *options) checkServiceTier() error {
+	switch o.serviceTier {
+	case "", ServiceTierAuto, ServiceTierDefault, ServiceTierFlex, ServiceTierPriority:
+		return nil
+	}
+	return fmt.Errorf("unknown service tier %q", o.serviceTier)
+}
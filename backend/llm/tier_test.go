@@ -0,0 +1,50 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIClient_WithServiceTier(t *testing.T) {
+	var sent ServiceTier
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req openAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		sent = req.ServiceTier
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(openAIResponse{
+			Choices:     []openAIChoice{{Message: openAIMessage{Role: "assistant", Content: "ok"}}},
+			ServiceTier: ServiceTierFlex,
+		})
+	})
+
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithServiceTier(ServiceTierFlex))
+	result, err := client.Generate(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if sent != ServiceTierFlex || result.ServiceTier != ServiceTierFlex {
+		t.Errorf("sent %q, result %q", sent, result.ServiceTier)
+	}
+}
+
+func TestOpenAIClient_WithServiceTierInvalid(t *testing.T) {
+	var calls int
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		writeChatResponse(w, "ok")
+	})
+
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithServiceTier("cheap"))
+	_, err := client.GenerateCode(context.Background(), "hello")
+	if err == nil || !strings.Contains(err.Error(), "unknown service tier") {
+		t.Fatalf("expected unknown service tier error, got %v", err)
+	}
+	if calls != 0 {
+		t.Error("request with invalid tier was sent")
+	}
+}
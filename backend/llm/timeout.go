@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"context"
+	"time"
+)
+
+type perCallTimeoutKey struct{}
+
+// WithPerCallTimeout returns a context that makes a client call using it
+// time out after d instead of the client's WithTimeout default. It lets a
+// shared client serve call sites with different time limits. A deadline
+// already on ctx still applies, whichever is sooner wins. A d of zero
+// disables the client timeout for the call.
+func WithPerCallTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, perCallTimeoutKey{}, d)
+}
+
+// callContext bounds a single call, retries and stream reads included, by
+// the per-call timeout from ctx or else the client default.
+func (o *options) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := o.timeout
+	if d, ok := ctx.Value(perCallTimeoutKey{}).(time.Duration); ok {
+		timeout = d
+	}
+
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// TimeoutProvider bounds every GenerateCode call to a fixed maximum,
+// whatever deadline the caller's context carries. The shorter of the two
+// wins. A max of zero or less adds no timeout, as with WithTimeout.
+type TimeoutProvider struct {
+	provider Provider
+	max      time.Duration
+}
+
+func NewTimeoutProvider(p Provider, max time.Duration) *TimeoutProvider {
+	return &TimeoutProvider{provider: p, max: max}
+}
+
+func (t *TimeoutProvider) GenerateCode(ctx context.Context, prompt string) (string, error) {
+	if t.max <= 0 {
+		return t.provider.GenerateCode(ctx, prompt)
+	}
+	ctx, cancel := context.WithTimeout(ctx, t.max)
+	defer cancel()
+	return t.provider.GenerateCode(ctx, prompt)
+}
@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func slowChatHandler(delay time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-time.After(delay):
+			writeChatResponse(w, "ok")
+		case <-r.Context().Done():
+		}
+	}
+}
+
+func TestOpenAIClient_DefaultTimeout(t *testing.T) {
+	server := newTestServer(t, slowChatHandler(time.Second))
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithTimeout(50*time.Millisecond))
+
+	_, err := client.GenerateCode(context.Background(), "hello")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestOpenAIClient_PerCallTimeout(t *testing.T) {
+	server := newTestServer(t, slowChatHandler(200*time.Millisecond))
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithTimeout(50*time.Millisecond))
+
+	t.Run("longer than default", func(t *testing.T) {
+		ctx := WithPerCallTimeout(context.Background(), 5*time.Second)
+		if _, err := client.GenerateCode(ctx, "hello"); err != nil {
+			t.Fatalf("GenerateCode failed: %v", err)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ctx := WithPerCallTimeout(context.Background(), 0)
+		if _, err := client.GenerateCode(ctx, "hello"); err != nil {
+			t.Fatalf("GenerateCode failed: %v", err)
+		}
+	})
+
+	t.Run("context deadline still applies", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		ctx = WithPerCallTimeout(ctx, 5*time.Second)
+
+		if _, err := client.GenerateCode(ctx, "hello"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+		}
+	})
+}
+
+func TestTimeoutProvider(t *testing.T) {
+	server := newTestServer(t, slowChatHandler(time.Second))
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithTimeout(0))
+	p := NewTimeoutProvider(client, 50*time.Millisecond)
+
+	start := time.Now()
+	_, err := p.GenerateCode(context.Background(), "hello")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("call took %v despite 50ms ceiling", elapsed)
+	}
+
+	// A shorter caller deadline still wins.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ctx = WithPerCallTimeout(ctx, 0)
+	p = NewTimeoutProvider(client, time.Hour)
+
+	start = time.Now()
+	if _, err := p.GenerateCode(ctx, "hello"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("caller deadline ignored, took %v", elapsed)
+	}
+}
+
+func TestTimeoutProvider_ZeroMax(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeChatResponse(w, "ok")
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	for _, max := range []time.Duration{0, -time.Second} {
+		if _, err := NewTimeoutProvider(client, max).GenerateCode(context.Background(), "hello"); err != nil {
+			t.Errorf("max %v: expected no timeout, got %v", max, err)
+		}
+	}
+}
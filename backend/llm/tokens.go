@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+var (
+	loaderOnce sync.Once
+	encodings  sync.Map // model -> *tiktoken.Tiktoken
+)
+
+// EstimateTokens counts the tokens text uses for model. OpenAI models are
+// counted with their real BPE encoding (embedded, no network access);
+// unknown models fall back to the chars/4 heuristic.
+func EstimateTokens(model, text string) (int, error) {
+	loaderOnce.Do(func() {
+		tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+	})
+
+	if enc, ok := encodings.Load(model); ok {
+		return len(enc.(*tiktoken.Tiktoken).EncodeOrdinary(text)), nil
+	}
+
+	if !hasTiktokenEncoding(model) {
+		return estimateTokensHeuristic(text), nil
+	}
+
+	enc, err := tiktoken.EncodingForModel(model)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load tokenizer for %s: %w", model, err)
+	}
+	encodings.Store(model, enc)
+
+	return len(enc.EncodeOrdinary(text)), nil
+}
+
+func hasTiktokenEncoding(model string) bool {
+	if _, ok := tiktoken.MODEL_TO_ENCODING[model]; ok {
+		return true
+	}
+	for prefix := range tiktoken.MODEL_PREFIX_TO_ENCODING {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func estimateTokensHeuristic(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// countMessageTokens estimates the prompt size of messages, including the
+// small per-message overhead of the chat format.
+func countMessageTokens(model string, messages []Message) (int, error) {
+	const perMessageOverhead = 4
+
+	total := 0
+	for _, m := range messages {
+		n, err := EstimateTokens(model, m.Content)
+		if err != nil {
+			return 0, err
+		}
+		total += n + perMessageOverhead
+	}
+	return total, nil
+}
+
+// checkPromptSize rejects messages that exceed the limit from promptLimit.
+func (o *options) checkPromptSize(model string, messages []Message) error {
+	limit := o.promptLimit(model)
+	if limit <= 0 {
+		return nil
+	}
+
+	n, err := countMessageTokens(model, messages)
+	if err != nil {
+		return err
+	}
+	if n > limit {
+		return fmt.Errorf("%w: %d tokens exceeds limit of %d", ErrPromptTooLarge, n, limit)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		model string
+		text  string
+		want  int
+	}{
+		{"gpt-4", "hello world", 2},
+		{"gpt-4o", "hello world", 2},
+		{"gpt-4o-mini-2024-07-18", "hello world", 2},
+		{"some-local-model", "hello world", 3},
+		{"gpt-4", "", 0},
+	}
+
+	for _, tt := range tests {
+		got, err := EstimateTokens(tt.model, tt.text)
+		if err != nil {
+			t.Fatalf("EstimateTokens(%q) failed: %v", tt.model, err)
+		}
+		if got != tt.want {
+			t.Errorf("EstimateTokens(%q, %q) = %d, want %d", tt.model, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEstimateTokensSpecialTokenText(t *testing.T) {
+	if _, err := EstimateTokens("gpt-4", "<|endoftext|>"); err != nil {
+		t.Fatalf("EstimateTokens failed: %v", err)
+	}
+}
+
+func TestOpenAIClient_MaxPromptTokens(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request should not be sent")
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithMaxPromptTokens(500))
+
+	_, err := client.GenerateCode(context.Background(), strings.Repeat("word ", 1000))
+	if !errors.Is(err, ErrPromptTooLarge) {
+		t.Fatalf("expected ErrPromptTooLarge, got %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrToolCallRequested = errors.New("model requested a tool call")
+	ErrToolLoopLimit     = errors.New("tool call limit reached")
+)
+
+// defaultMaxToolRounds caps how many times the model may call tools in
+// one generation.
+const defaultMaxToolRounds = 5
+
+// Tool describes a function the model may call. Parameters is its JSON
+// Schema.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  json.RawMessage
+}
+
+// ToolHandler runs a tool call with the model's JSON arguments and returns
+// the result passed back to the model.
+type ToolHandler func(ctx context.Context, arguments string) (string, error)
+
+// WithTools lets OpenAI models call tools during generation. Each tool call
+// is run with the handler of the same name and its result sent back, until
+// the model answers with content or the WithMaxToolRounds limit is hit.
+// Streaming calls don't offer tools.
+func WithTools(tools []Tool, handlers map[string]ToolHandler) Option {
+	return func(o *options) {
+		o.tools = tools
+		o.toolHandlers = handlers
+	}
+}
+
+// WithMaxToolRounds caps the tool call round trips per generation. The
+// default is 5; going past it fails with ErrToolLoopLimit.
+func WithMaxToolRounds(n int) Option {
+	return func(o *options) {
+		o.maxToolRounds = n
+	}
+}
+
+// ToolCall is a function call the model asked for instead of answering.
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// ToolCallError is returned when the model responds with tool calls rather
+// than content. It matches ErrToolCallRequested with errors.Is.
+type ToolCallError struct {
+	Calls []ToolCall
+	Usage Usage
+}
+
+func (e *ToolCallError) Error() string {
+	names := make([]string, len(e.Calls))
+	for i, c := range e.Calls {
+		names[i] = c.Name
+	}
+	return fmt.Sprintf("%s: %s", ErrToolCallRequested, strings.Join(names, ", "))
+}
+
+func (e *ToolCallError) Is(target error) bool {
+	return target == ErrToolCallRequested
+}
+
+type openAITool struct {
+	Type     string             `json:"type"`
+	Function openAIFunctionSpec `json:"function"`
+}
+
+type openAIFunctionSpec struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string             `json:"id"`
+	Type     string             `json:"type"`
+	Function openAIFunctionCall `json:"function"`
+}
+
+type openAIFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// openAIToolCallDelta is a streamed fragment of a tool call. Fragments
+// sharing an index belong to the same call.
+type openAIToolCallDelta struct {
+	Index    int                `json:"index"`
+	ID       string             `json:"id"`
+	Function openAIFunctionCall `json:"function"`
+}
+
+func (o *options) openAITools() []openAITool {
+	if len(o.tools) == 0 {
+		return nil
+	}
+
+	out := make([]openAITool, len(o.tools))
+	for i, t := range o.tools {
+		out[i] = openAITool{Type: "function", Function: openAIFunctionSpec(t)}
+	}
+	return out
+}
+
+// runTools calls the handlers for calls and returns the assistant turn that
+// requested them followed by one tool message per result.
+func (o *options) runTools(ctx context.Context, calls []ToolCall) ([]openAIMessage, error) {
+	assistant := openAIMessage{Role: string(RoleAssistant)}
+	results := make([]openAIMessage, 0, len(calls))
+
+	for _, c := range calls {
+		assistant.ToolCalls = append(assistant.ToolCalls, openAIToolCall{
+			ID:       c.ID,
+			Type:     "function",
+			Function: openAIFunctionCall{Name: c.Name, Arguments: c.Arguments},
+		})
+
+		handler, ok := o.toolHandlers[c.Name]
+		if !ok {
+			return nil, fmt.Errorf("model called unknown tool %q", c.Name)
+		}
+		out, err := handler(ctx, c.Arguments)
+		if err != nil {
+			return nil, fmt.Errorf("tool %q failed: %w", c.Name, err)
+		}
+		results = append(results, openAIMessage{Role: "tool", ToolCallID: c.ID, Content: out})
+	}
+
+	return append([]openAIMessage{assistant}, results...), nil
+}
+
+func toToolCalls(calls []openAIToolCall) []ToolCall {
+	out := make([]ToolCall, len(calls))
+	for i, c := range calls {
+		out[i] = ToolCall{ID: c.ID, Name: c.Function.Name, Arguments: c.Function.Arguments}
+	}
+	return out
+}
+
+// toolCallAccumulator reassembles tool calls from stream deltas.
+type toolCallAccumulator struct {
+	calls []ToolCall
+}
+
+func (a *toolCallAccumulator) add(d openAIToolCallDelta) {
+	for len(a.calls) <= d.Index {
+		a.calls = append(a.calls, ToolCall{})
+	}
+
+	c := &a.calls[d.Index]
+	if d.ID != "" {
+		c.ID = d.ID
+	}
+	c.Name += d.Function.Name
+	c.Arguments += d.Function.Arguments
+}
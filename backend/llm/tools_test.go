@@ -0,0 +1,156 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIClient_ToolCallRequested(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"finish_reason":"tool_calls","message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]}}]}`))
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	_, err := client.GenerateCode(context.Background(), "weather page")
+	if !errors.Is(err, ErrToolCallRequested) {
+		t.Fatalf("expected ErrToolCallRequested, got %v", err)
+	}
+
+	var toolErr *ToolCallError
+	if !errors.As(err, &toolErr) {
+		t.Fatalf("expected *ToolCallError, got %T", err)
+	}
+	want := ToolCall{ID: "call_1", Name: "get_weather", Arguments: `{"city":"Paris"}`}
+	if len(toolErr.Calls) != 1 || toolErr.Calls[0] != want {
+		t.Errorf("unexpected calls: %+v", toolErr.Calls)
+	}
+}
+
+func TestOpenAIClient_StreamToolCallRequested(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunks := []string{
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"get_weather","arguments":""}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]}}]}`,
+			`{"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+		}
+		for _, c := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL))
+
+	var out strings.Builder
+	err := client.GenerateCodeStream(context.Background(), "weather page", &out)
+
+	var toolErr *ToolCallError
+	if !errors.As(err, &toolErr) {
+		t.Fatalf("expected *ToolCallError, got %v", err)
+	}
+	want := ToolCall{ID: "call_1", Name: "get_weather", Arguments: `{"city":"Paris"}`}
+	if len(toolErr.Calls) != 1 || toolErr.Calls[0] != want {
+		t.Errorf("unexpected calls: %+v", toolErr.Calls)
+	}
+}
+
+func TestOpenAIClient_ToolLoop(t *testing.T) {
+	var rounds int
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req openAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		rounds++
+
+		if len(req.Tools) != 1 || req.Tools[0].Function.Name != "get_weather" {
+			t.Errorf("tools not sent: %+v", req.Tools)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if rounds == 1 {
+			w.Write([]byte(`{"choices":[{"finish_reason":"tool_calls","message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]}}],"usage":{"prompt_tokens":10,"completion_tokens":5}}`))
+			return
+		}
+
+		n := len(req.Messages)
+		assistant, tool := req.Messages[n-2], req.Messages[n-1]
+		if len(assistant.ToolCalls) != 1 || assistant.ToolCalls[0].ID != "call_1" {
+			t.Errorf("assistant tool call not echoed: %+v", assistant)
+		}
+		if tool.Role != "tool" || tool.ToolCallID != "call_1" || tool.Content != "18°C, sunny" {
+			t.Errorf("unexpected tool message: %+v", tool)
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"<p>18°C, sunny</p>"}}],"usage":{"prompt_tokens":20,"completion_tokens":8}}`))
+	})
+
+	var gotArgs string
+	tools := []Tool{{Name: "get_weather", Description: "Current weather", Parameters: json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}}}`)}}
+	handlers := map[string]ToolHandler{
+		"get_weather": func(ctx context.Context, arguments string) (string, error) {
+			gotArgs = arguments
+			return "18°C, sunny", nil
+		},
+	}
+	client := NewOpenAIClient("test-key", "gpt-4o", WithBaseURL(server.URL), WithTools(tools, handlers))
+
+	result, err := client.Generate(context.Background(), "weather widget")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if result.Code != "<p>18°C, sunny</p>" {
+		t.Errorf("unexpected code: %q", result.Code)
+	}
+	if gotArgs != `{"city":"Paris"}` {
+		t.Errorf("handler got %q", gotArgs)
+	}
+	if result.Usage != (Usage{PromptTokens: 30, CompletionTokens: 13}) {
+		t.Errorf("usage should cover all rounds, got %+v", result.Usage)
+	}
+}
+
+func TestOpenAIClient_ToolLoopLimit(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"finish_reason":"tool_calls","message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"noop","arguments":"{}"}}]}}]}`))
+	})
+
+	calls := 0
+	handlers := map[string]ToolHandler{
+		"noop": func(ctx context.Context, arguments string) (string, error) {
+			calls++
+			return "ok", nil
+		},
+	}
+	client := NewOpenAIClient("test-key", "gpt-4o", WithBaseURL(server.URL),
+		WithTools([]Tool{{Name: "noop"}}, handlers), WithMaxToolRounds(2))
+
+	_, err := client.GenerateCode(context.Background(), "loop forever")
+	if !errors.Is(err, ErrToolLoopLimit) {
+		t.Fatalf("expected ErrToolLoopLimit, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 handler calls, got %d", calls)
+	}
+}
+
+func TestOpenAIClient_ToolUnknown(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"finish_reason":"tool_calls","message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"rm_rf","arguments":"{}"}}]}}]}`))
+	})
+	handlers := map[string]ToolHandler{
+		"noop": func(ctx context.Context, arguments string) (string, error) { return "", nil },
+	}
+	client := NewOpenAIClient("test-key", "gpt-4o", WithBaseURL(server.URL), WithTools([]Tool{{Name: "noop"}}, handlers))
+
+	_, err := client.GenerateCode(context.Background(), "hello")
+	if err == nil || !strings.Contains(err.Error(), `unknown tool "rm_rf"`) {
+		t.Fatalf("expected unknown tool error, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+)
+
+// WithHTTPTrace writes every HTTP exchange to w: the request with headers
+// and body, then the response status, headers and body. Secret headers
+// such as Authorization are always redacted. Response bodies are copied
+// as the client reads them, so streams still arrive incrementally.
+//
+// This is a development aid independent of WithLogger; exchanges from
+// concurrent calls can interleave.
+func WithHTTPTrace(w io.Writer) Option {
+	return func(o *options) {
+		o.traceWriter = w
+	}
+}
+
+type traceTransport struct {
+	base http.RoundTripper
+	mu   sync.Mutex
+	w    io.Writer
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	dump, err := redactedRequestDump(req)
+	if err != nil {
+		return nil, err
+	}
+	t.write(dump)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		t.write(fmt.Appendf(nil, "\n<<< %s\n\n", err))
+		return nil, err
+	}
+
+	head, err := httputil.DumpResponse(resp, false)
+	if err != nil {
+		return resp, nil
+	}
+	t.write(append([]byte("\n<<<\n"), head...))
+	resp.Body = &traceBody{ReadCloser: resp.Body, t: t}
+	return resp, nil
+}
+
+func (t *traceTransport) write(p []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.w.Write(p)
+}
+
+// redactedRequestDump dumps req as sent on the wire without consuming its
+// body or leaking secret headers.
+func redactedRequestDump(req *http.Request) ([]byte, error) {
+	clone := req.Clone(req.Context())
+	for name := range clone.Header {
+		if secretHeaders[http.CanonicalHeaderKey(name)] {
+			clone.Header.Set(name, "[redacted]")
+		}
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			clone.Body = body
+		} else {
+			data, err := io.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			req.Body = io.NopCloser(bytes.NewReader(data))
+			clone.Body = io.NopCloser(bytes.NewReader(data))
+		}
+	}
+
+	dump, err := httputil.DumpRequestOut(clone, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dump request: %w", err)
+	}
+	return append([]byte("\n>>>\n"), dump...), nil
+}
+
+type traceBody struct {
+	io.ReadCloser
+	t *traceTransport
+}
+
+func (b *traceBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.t.write(p[:n])
+	}
+	return n, err
+}
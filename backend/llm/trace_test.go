@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/egedolmaci/scaffolder/backend/llm/testutil"
+)
+
+func TestOpenAIClient_WithHTTPTrace(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeChatResponse(w, "<html>traced</html>")
+	})
+
+	var trace bytes.Buffer
+	client := NewOpenAIClient("sk-secret-123", "gpt-4", WithBaseURL(server.URL), WithHTTPTrace(&trace))
+
+	if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+
+	out := trace.String()
+	for _, want := range []string{
+		"POST /chat/completions HTTP/1.1",
+		"Authorization: [redacted]",
+		`"model":"gpt-4"`,
+		"HTTP/1.1 200 OK",
+		"Content-Type: application/json",
+		"traced",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("trace missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "sk-secret-123") {
+		t.Errorf("trace leaked the API key:\n%s", out)
+	}
+}
+
+func TestOpenAIClient_WithHTTPTraceStream(t *testing.T) {
+	server := testutil.NewSSEServer(t, testutil.SSEConfig{Chunks: testutil.OpenAIDeltas("<html>", "</html>")})
+
+	var trace bytes.Buffer
+	client := NewOpenAIClient("sk-secret-123", "gpt-4", WithBaseURL(server.URL), WithHTTPTrace(&trace))
+
+	var out strings.Builder
+	if err := client.GenerateCodeStream(context.Background(), "hello", &out); err != nil {
+		t.Fatalf("GenerateCodeStream failed: %v", err)
+	}
+	if out.String() != "<html></html>" {
+		t.Errorf("stream output = %q", out.String())
+	}
+	if !strings.Contains(trace.String(), "data: [DONE]") {
+		t.Errorf("trace missing stream body:\n%s", trace.String())
+	}
+}
@@ -0,0 +1,92 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/egedolmaci/scaffolder/backend/parser"
+)
+
+// ResponseValidator checks generated code, as extracted from the first
+// fenced block of the response, and returns an error if it is unusable.
+// lang is the block's language tag, "" if it has none or the response
+// isn't fenced, in which case code is the whole response.
+type ResponseValidator func(code, lang string) error
+
+// WithResponseValidator fails generations v rejects. It can be given
+// several times; validators run in order and the first failure wins. The
+// failure wraps ErrInvalidResponse and v's error. With WithRetries a
+// rejected generation is regenerated up to that many times, without
+// backoff, before failing. Validators don't see streamed output.
+func WithResponseValidator(v ResponseValidator) Option {
+	return func(o *options) {
+		o.validators = append(o.validators, v)
+	}
+}
+
+// WithMinOutputLength is WithResponseValidator(MinLength(n)).
+func WithMinOutputLength(n int) Option {
+	return WithResponseValidator(MinLength(n))
+}
+
+// MinLength rejects code shorter than n characters once whitespace is
+// trimmed, catching stubs like an empty <html></html> that are otherwise
+// valid. The error wraps ErrOutputTooShort.
+func MinLength(n int) ResponseValidator {
+	return func(code, lang string) error {
+		if got := utf8.RuneCountInString(strings.TrimSpace(code)); got < n {
+			return fmt.Errorf("%w: %d characters, want at least %d", ErrOutputTooShort, got, n)
+		}
+		return nil
+	}
+}
+
+// NoExternalResources rejects code that loads resources from another
+// origin, see WithExternalResources. The error wraps ErrExternalResource
+// and lists the URLs.
+func NoExternalResources() ResponseValidator {
+	return func(code, lang string) error {
+		if urls := parser.ExternalResources(code); len(urls) > 0 {
+			return fmt.Errorf("%w: %s", ErrExternalResource, strings.Join(urls, ", "))
+		}
+		return nil
+	}
+}
+
+// CompleteHTML rejects HTML that doesn't have a complete body, as left by
+// a response cut off at the token limit. Code tagged with another language
+// passes.
+func CompleteHTML() ResponseValidator {
+	return func(code, lang string) error {
+		if lang != "" && !strings.EqualFold(lang, "html") {
+			return nil
+		}
+		if !parser.HasCompleteBody(code) {
+			return errors.New("incomplete HTML document")
+		}
+		return nil
+	}
+}
+
+// responseValidators returns the validators to run, the built-in policies
+// first.
+func (o *options) responseValidators() []ResponseValidator {
+	if o.externalResources != ExternalResourcesReject {
+		return o.validators
+	}
+	return append([]ResponseValidator{NoExternalResources()}, o.validators...)
+}
+
+// generate calls send, regenerating output a validator rejected up to the
+// retry count. Regenerations are drawn from ctx's retry budget.
+func (o *options) generate(ctx context.Context, send func() (*GenerationResult, error)) (*GenerationResult, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := send()
+		if attempt >= o.retry.maxRetries || !errors.Is(err, ErrInvalidResponse) || !takeRetry(ctx) {
+			return result, err
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWithMinOutputLength(t *testing.T) {
+	var calls atomic.Int32
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			writeChatResponse(w, "```html\n<html></html>\n```")
+			return
+		}
+		writeChatResponse(w, "<html><body><h1>Todo</h1></body></html>")
+	})
+
+	t.Run("fails", func(t *testing.T) {
+		calls.Store(0)
+		client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithMinOutputLength(20))
+		_, err := client.GenerateCode(context.Background(), "hello")
+		if !errors.Is(err, ErrOutputTooShort) {
+			t.Fatalf("expected ErrOutputTooShort, got %v", err)
+		}
+		if n := calls.Load(); n != 1 {
+			t.Errorf("expected no regeneration without retries, got %d calls", n)
+		}
+	})
+
+	t.Run("regenerates", func(t *testing.T) {
+		calls.Store(0)
+		client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithMinOutputLength(20), WithRetries(2))
+		got, err := client.GenerateCode(context.Background(), "hello")
+		if err != nil {
+			t.Fatalf("GenerateCode failed: %v", err)
+		}
+		if got != "<html><body><h1>Todo</h1></body></html>" || calls.Load() != 3 {
+			t.Errorf("got %q after %d calls", got, calls.Load())
+		}
+	})
+
+	t.Run("budget exhausted", func(t *testing.T) {
+		calls.Store(0)
+		budget := NewRetryBudget(1)
+		client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithMinOutputLength(20), WithRetries(2))
+		_, err := client.GenerateCode(WithRetryBudget(context.Background(), budget), "hello")
+		if !errors.Is(err, ErrOutputTooShort) {
+			t.Fatalf("expected ErrOutputTooShort, got %v", err)
+		}
+		if n := calls.Load(); n != 2 || budget.Remaining() != 0 {
+			t.Errorf("expected one budgeted regeneration, got %d calls and %d retries left", n, budget.Remaining())
+		}
+	})
+}
+
+func TestWithResponseValidator(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeChatResponse(w, "```js\nconsole.log(1)\n```")
+	})
+
+	var gotCode, gotLang string
+	errNoMain := errors.New("no main function")
+	var calls int
+	client := NewOpenAIClient("test-key", "gpt-4",
+		WithBaseURL(server.URL),
+		WithRetries(1),
+		WithResponseValidator(func(code, lang string) error {
+			gotCode, gotLang = code, lang
+			return nil
+		}),
+		WithResponseValidator(func(code, lang string) error {
+			calls++
+			return errNoMain
+		}),
+	)
+
+	_, err := client.GenerateCode(context.Background(), "hello")
+	if !errors.Is(err, ErrInvalidResponse) || !errors.Is(err, errNoMain) {
+		t.Fatalf("expected ErrInvalidResponse wrapping the validator error, got %v", err)
+	}
+	if gotCode != "console.log(1)" || gotLang != "js" {
+		t.Errorf("validator got code %q lang %q", gotCode, gotLang)
+	}
+	if calls != 2 {
+		t.Errorf("expected one regeneration, validator ran %d times", calls)
+	}
+}
+
+func TestBuiltinValidators(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator ResponseValidator
+		code      string
+		lang      string
+		want      error
+	}{
+		{"min length ok", MinLength(5), "<p>hello</p>", "html", nil},
+		{"min length short", MinLength(20), "  <html></html>  ", "html", ErrOutputTooShort},
+		{"external ok", NoExternalResources(), "<img src=\"a.png\">", "html", nil},
+		{"external", NoExternalResources(), "<script src=\"https://cdn.example.com/x.js\"></script>", "html", ErrExternalResource},
+		{"complete", CompleteHTML(), "<html><body></body></html>", "html", nil},
+		{"other language", CompleteHTML(), "body {}", "css", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.validator(tt.code, tt.lang); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if err := CompleteHTML()("<html><body><div>", ""); err == nil || !strings.Contains(err.Error(), "incomplete") {
+		t.Errorf("expected a truncated page to fail, got %v", err)
+	}
+}
@@ -0,0 +1,8 @@
+package llm
+
+// Version is the version of this package. It is sent in the User-Agent of
+// every request, as scaffolder-llm/<Version>, so server-side logs show
+// which release a client runs.
+const Version = "0.1.0"
+
+const userAgent = "scaffolder-llm/" + Version
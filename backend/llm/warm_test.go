@@ -0,0 +1,84 @@
+package llm
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestOpenAIClient_Warm(t *testing.T) {
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/models" {
+			if r.Header.Get("Authorization") != "Bearer test-key" {
+				t.Errorf("warm request not authorized: %v", r.Header)
+			}
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeChatResponse(w, "ok")
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithInsecureSkipTLSVerify(true))
+	if err := client.Warm(context.Background()); err != nil {
+		t.Fatalf("Warm failed: %v", err)
+	}
+	if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("expected the warmed connection to be reused, got %d connections", n)
+	}
+}
+
+func TestOpenAIClient_WarmUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(url))
+	if err := client.Warm(context.Background()); err == nil {
+		t.Fatal("expected an error for an unreachable API")
+	}
+}
+
+// BenchmarkWarm compares the first request on a fresh client with and
+// without a prior Warm, against a local TLS server.
+func BenchmarkWarm(b *testing.B) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeChatResponse(w, "ok")
+	}))
+	b.Cleanup(server.Close)
+
+	for _, warm := range []bool{false, true} {
+		name := "cold"
+		if warm {
+			name = "warm"
+		}
+		b.Run(name, func(b *testing.B) {
+			for b.Loop() {
+				b.StopTimer()
+				client := NewOpenAIClient("test-key", "gpt-4", WithBaseURL(server.URL), WithInsecureSkipTLSVerify(true))
+				if warm {
+					client.Warm(context.Background())
+				}
+				b.StartTimer()
+
+				if _, err := client.GenerateCode(context.Background(), "hello"); err != nil {
+					b.Fatal(err)
+				}
+				client.httpClient.CloseIdleConnections()
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"strings"
+	"sync"
+)
+
+// modelContextWindows maps model name prefixes to their context window in
+// tokens, prompt and output combined. Longer prefixes are listed before
+// shorter ones they extend.
+var modelContextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"gpt-5", 400000},
+	{"gpt-4o", 128000},
+	{"gpt-4.1", 1047576},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo", 16385},
+	{"o1-mini", 128000},
+	{"o1", 200000},
+	{"o3", 200000},
+	{"o4-mini", 200000},
+	{"claude-", 200000},
+	{"gemini-1.5-pro", 2097152},
+	{"gemini-1.5-flash", 1048576},
+	{"gemini-2", 1048576},
+}
+
+var (
+	contextWindowMu        sync.RWMutex
+	contextWindowOverrides = map[string]int{}
+)
+
+// ModelContextWindow returns the context window of model in tokens. Models
+// set with SetModelContextWindow take precedence over the built-in table.
+func ModelContextWindow(model string) (int, bool) {
+	contextWindowMu.RLock()
+	tokens, ok := contextWindowOverrides[model]
+	contextWindowMu.RUnlock()
+	if ok {
+		return tokens, true
+	}
+
+	for _, w := range modelContextWindows {
+		if strings.HasPrefix(model, w.prefix) {
+			return w.tokens, true
+		}
+	}
+	return 0, false
+}
+
+// SetModelContextWindow sets the context window for the exact model name,
+// e.g. a self-hosted model. A non-positive tokens removes the override.
+func SetModelContextWindow(model string, tokens int) {
+	contextWindowMu.Lock()
+	defer contextWindowMu.Unlock()
+	if tokens <= 0 {
+		delete(contextWindowOverrides, model)
+		return
+	}
+	contextWindowOverrides[model] = tokens
+}
+
+// promptLimit returns the prompt token limit for model: the context window
+// less the output reserved by max_tokens, lowered further by
+// WithMaxPromptTokens. Zero means no limit is known.
+func (o *options) promptLimit(model string) int {
+	limit := o.maxPromptTokens
+	reserved := o.outputTokens()
+	if window, ok := ModelContextWindow(model); ok && window > reserved {
+		if available := window - reserved; limit <= 0 || available < limit {
+			limit = available
+		}
+	}
+	return limit
+}
+
+// outputTokens returns the max_tokens requests are sent with: the
+// WithMaxTokens value, or else the provider's default.
+func (o *options) outputTokens() int {
+	if o.maxTokens > 0 {
+		return o.maxTokens
+	}
+	return o.defaultMaxTokens
+}
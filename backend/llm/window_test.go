@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestModelContextWindow(t *testing.T) {
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{"gpt-4", 8192},
+		{"gpt-4o-mini", 128000},
+		{"gpt-4.1-nano", 1047576},
+		{"o1-mini", 128000},
+		{"claude-sonnet-4-20250514", 200000},
+		{"gemini-2.5-pro", 1048576},
+	}
+	for _, tt := range tests {
+		if got, ok := ModelContextWindow(tt.model); !ok || got != tt.want {
+			t.Errorf("ModelContextWindow(%q) = %d, %v; want %d", tt.model, got, ok, tt.want)
+		}
+	}
+
+	if _, ok := ModelContextWindow("llama-3-local"); ok {
+		t.Error("unknown model should not have a window")
+	}
+}
+
+func TestSetModelContextWindow(t *testing.T) {
+	t.Cleanup(func() { SetModelContextWindow("llama-3-local", 0) })
+
+	SetModelContextWindow("llama-3-local", 100)
+	if got, ok := ModelContextWindow("llama-3-local"); !ok || got != 100 {
+		t.Fatalf("override = %d, %v", got, ok)
+	}
+
+	opts := defaultOptions()
+	messages := []Message{{Role: RoleUser, Content: strings.Repeat("word ", 200)}}
+	if err := opts.checkPromptSize("llama-3-local", messages); !errors.Is(err, ErrPromptTooLarge) {
+		t.Errorf("expected ErrPromptTooLarge, got %v", err)
+	}
+
+	SetModelContextWindow("llama-3-local", 0)
+	if _, ok := ModelContextWindow("llama-3-local"); ok {
+		t.Error("override should be removed")
+	}
+}
+
+func TestPromptLimitReservesOutput(t *testing.T) {
+	opts := defaultOptions()
+	WithMaxTokens(4000)(&opts)
+	if got := opts.promptLimit("gpt-4"); got != 4192 {
+		t.Errorf("promptLimit = %d, want 4192", got)
+	}
+
+	WithMaxPromptTokens(1000)(&opts)
+	if got := opts.promptLimit("gpt-4"); got != 1000 {
+		t.Errorf("promptLimit = %d, want 1000", got)
+	}
+}
+
+func TestAnthropicClient_PromptLimitReservesDefaultMaxTokens(t *testing.T) {
+	client := NewAnthropicClient("test-key", "claude-sonnet-4-20250514")
+	if got := client.opts.promptLimit("claude-sonnet-4-20250514"); got != 200000-defaultAnthropicMaxTokens {
+		t.Errorf("promptLimit = %d, want %d", got, 200000-defaultAnthropicMaxTokens)
+	}
+
+	client = NewAnthropicClient("test-key", "claude-sonnet-4-20250514", WithMaxTokens(1000))
+	if got := client.opts.promptLimit("claude-sonnet-4-20250514"); got != 199000 {
+		t.Errorf("promptLimit = %d, want 199000", got)
+	}
+}
+
+func TestOpenAIClient_StreamReconnectStopsAtContextWindow(t *testing.T) {
+	t.Cleanup(func() { SetModelContextWindow("llama-3-local", 0) })
+	SetModelContextWindow("llama-3-local", 200)
+
+	var calls int
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", strings.Repeat("<p>hi</p>", 100))
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	})
+
+	client := NewOpenAIClient("test-key", "llama-3-local", WithBaseURL(server.URL), WithStreamReconnect(3))
+
+	var out strings.Builder
+	if err := client.GenerateCodeStream(context.Background(), "hello", &out); err == nil {
+		t.Fatal("expected the interrupted stream to fail")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, reconnect should be skipped", calls)
+	}
+}
@@ -0,0 +1,180 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const formatIndent = "  "
+
+// inlineElements are the elements laid out in the flow of text, where
+// adding or removing whitespace around them can change the rendered page.
+var inlineElements = map[atom.Atom]bool{
+	atom.A: true, atom.Abbr: true, atom.Audio: true, atom.B: true, atom.Bdi: true,
+	atom.Bdo: true, atom.Br: true, atom.Button: true, atom.Canvas: true, atom.Cite: true,
+	atom.Code: true, atom.Data: true, atom.Dfn: true, atom.Em: true, atom.I: true,
+	atom.Iframe: true, atom.Img: true, atom.Input: true, atom.Kbd: true, atom.Label: true,
+	atom.Mark: true, atom.Math: true, atom.Meter: true, atom.Object: true, atom.Output: true,
+	atom.Picture: true, atom.Progress: true, atom.Q: true, atom.S: true, atom.Samp: true,
+	atom.Select: true, atom.Small: true, atom.Span: true, atom.Strong: true, atom.Sub: true,
+	atom.Sup: true, atom.Svg: true, atom.Textarea: true, atom.Time: true, atom.U: true,
+	atom.Var: true, atom.Video: true, atom.Wbr: true,
+}
+
+// verbatimElements keep their contents exactly as written.
+var verbatimElements = map[atom.Atom]bool{
+	atom.Pre: true, atom.Textarea: true, atom.Script: true, atom.Style: true,
+}
+
+// FormatHTML re-indents an HTML document, putting each block-level element
+// on its own line indented by two spaces per level. Line breaks are only
+// added or removed where whitespace doesn't affect rendering: elements
+// holding text or inline elements stay on one line, with runs of
+// whitespace collapsed, and the contents of <pre>, <textarea>, <script>
+// and <style> are kept verbatim. The input is parsed as a document, so a
+// fragment gets the <html>, <head> and <body> the parser adds.
+func FormatHTML(doc string) (string, error) {
+	root, err := html.Parse(strings.NewReader(doc))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse html: %w", err)
+	}
+
+	var out strings.Builder
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		if err := formatNode(&out, c, 0); err != nil {
+			return "", err
+		}
+	}
+	return out.String(), nil
+}
+
+func formatNode(w *strings.Builder, n *html.Node, depth int) error {
+	indent := strings.Repeat(formatIndent, depth)
+
+	switch n.Type {
+	case html.DoctypeNode, html.CommentNode:
+		w.WriteString(indent)
+		if err := html.Render(w, n); err != nil {
+			return fmt.Errorf("failed to render html: %w", err)
+		}
+		w.WriteByte('\n')
+		return nil
+	case html.ElementNode:
+	default:
+		return nil
+	}
+
+	if !blockLayout(n) {
+		collapseText(n, true)
+		w.WriteString(indent)
+		if err := html.Render(w, n); err != nil {
+			return fmt.Errorf("failed to render html: %w", err)
+		}
+		w.WriteByte('\n')
+		return nil
+	}
+
+	start, end, err := tags(n)
+	if err != nil {
+		return err
+	}
+	w.WriteString(indent + start + "\n")
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if err := formatNode(w, c, depth+1); err != nil {
+			return err
+		}
+	}
+	w.WriteString(indent + end + "\n")
+	return nil
+}
+
+// blockLayout reports whether n's children can each go on their own line
+// without changing how the page renders: n is itself block-level, holds
+// elements but no text, and every boundary between its children is either
+// already whitespace or next to a block-level element.
+func blockLayout(n *html.Node) bool {
+	if inlineElements[n.DataAtom] || verbatimElements[n.DataAtom] || n.Namespace != "" {
+		return false
+	}
+
+	var hasElement, prevBlock, space bool
+	first := true
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			if strings.TrimSpace(c.Data) != "" {
+				return false
+			}
+			space = true
+			continue
+		}
+
+		block := c.Type == html.ElementNode && !inlineElements[c.DataAtom]
+		if !first && !space && !prevBlock && !block {
+			return false
+		}
+
+		hasElement = hasElement || c.Type == html.ElementNode
+		first, prevBlock, space = false, block, false
+	}
+	return hasElement
+}
+
+// collapseText collapses whitespace runs in the text under n to single
+// spaces, outside verbatim elements. At the top level, where n starts and
+// ends a block, leading and trailing whitespace is dropped too.
+func collapseText(n *html.Node, top bool) {
+	if verbatimElements[n.DataAtom] {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.TextNode:
+			c.Data = collapseSpace(c.Data)
+		case html.ElementNode:
+			collapseText(c, false)
+		}
+	}
+
+	if top && !inlineElements[n.DataAtom] {
+		if c := n.FirstChild; c != nil && c.Type == html.TextNode {
+			c.Data = strings.TrimLeft(c.Data, " ")
+		}
+		if c := n.LastChild; c != nil && c.Type == html.TextNode {
+			c.Data = strings.TrimRight(c.Data, " ")
+		}
+	}
+}
+
+// collapseSpace replaces each run of HTML whitespace in s with one space.
+func collapseSpace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' {
+			if !space {
+				b.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		b.WriteRune(r)
+		space = false
+	}
+	return b.String()
+}
+
+// tags returns the start and end tags of element n as html.Render writes
+// them.
+func tags(n *html.Node) (start, end string, err error) {
+	shallow := &html.Node{Type: n.Type, DataAtom: n.DataAtom, Data: n.Data, Namespace: n.Namespace, Attr: n.Attr}
+
+	var b strings.Builder
+	if err := html.Render(&b, shallow); err != nil {
+		return "", "", fmt.Errorf("failed to render html: %w", err)
+	}
+	end = "</" + n.Data + ">"
+	return strings.TrimSuffix(b.String(), end), end, nil
+}
@@ -0,0 +1,44 @@
+package parser
+
+import "testing"
+
+func TestFormatHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "reindents blocks",
+			in:   "<!DOCTYPE html><html><head><title>Todo</title></head><body><div class=\"app\"><h1>Todos</h1>\n      <ul><li>one</li><li>two</li></ul></div></body></html>",
+			want: "<!DOCTYPE html>\n<html>\n  <head>\n    <title>Todo</title>\n  </head>\n  <body>\n    <div class=\"app\">\n      <h1>Todos</h1>\n      <ul>\n        <li>one</li>\n        <li>two</li>\n      </ul>\n    </div>\n  </body>\n</html>\n",
+		},
+		{
+			name: "keeps inline content on one line",
+			in:   "<html><body><p>\n    Hello <b>big</b><i>world</i>!\n  </p></body></html>",
+			want: "<html>\n  <head></head>\n  <body>\n    <p>Hello <b>big</b><i>world</i>!</p>\n  </body>\n</html>\n",
+		},
+		{
+			name: "adjacent inline elements",
+			in:   "<html><body><div><a href=\"/\">a</a><a href=\"/b\">b</a></div></body></html>",
+			want: "<html>\n  <head></head>\n  <body>\n    <div><a href=\"/\">a</a><a href=\"/b\">b</a></div>\n  </body>\n</html>\n",
+		},
+		{
+			name: "preserves verbatim whitespace",
+			in:   "<html><body><pre>  a\n    b</pre><textarea>\n x  y</textarea><script>\n  if (a)  {\n    b();\n  }\n</script></body></html>",
+			want: "<html>\n  <head></head>\n  <body>\n    <pre>  a\n    b</pre>\n    <textarea> x  y</textarea>\n    <script>\n  if (a)  {\n    b();\n  }\n</script>\n  </body>\n</html>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FormatHTML(tt.in)
+			if err != nil {
+				t.Fatalf("FormatHTML failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
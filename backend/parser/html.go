@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HasCompleteBody reports whether doc contains both an opening and closing
+// <body> tag. It tokenizes leniently, so a partially streamed document is
+// fine to pass in.
+func HasCompleteBody(doc string) bool {
+	z := html.NewTokenizer(strings.NewReader(doc))
+	var opened bool
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return false
+		case html.StartTagToken:
+			if name, _ := z.TagName(); string(name) == "body" {
+				opened = true
+			}
+		case html.EndTagToken:
+			if name, _ := z.TagName(); opened && string(name) == "body" {
+				return true
+			}
+		}
+	}
+}
+
+// InlineAssets merges separately generated CSS and JS into a single HTML
+// document: the CSS goes in a <style> at the end of <head> and the JS in a
+// <script> at the end of <body>. Missing <html>, <head> or <body> elements
+// (including an empty html argument) are created, and a doctype is added
+// if absent. Any "</script" in the JS or "</style" in the CSS is written as
+// "<\/script" or "<\/style", which reads the same inside their strings and
+// comments, so it can't end the element early.
+func InlineAssets(htmlDoc, css, js string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(htmlDoc))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse html: %w", err)
+	}
+
+	if doc.FirstChild == nil || doc.FirstChild.Type != html.DoctypeNode {
+		doc.InsertBefore(&html.Node{Type: html.DoctypeNode, Data: "html"}, doc.FirstChild)
+	}
+
+	head := findElement(doc, atom.Head)
+	body := findElement(doc, atom.Body)
+	if head == nil || body == nil {
+		return "", errors.New("parsed document has no head or body")
+	}
+
+	if strings.TrimSpace(css) != "" {
+		head.AppendChild(rawTextElement(atom.Style, "\n"+escapeEndTag(strings.TrimSpace(css), "style")+"\n"))
+	}
+	if strings.TrimSpace(js) != "" {
+		body.AppendChild(rawTextElement(atom.Script, "\n"+escapeEndTag(strings.TrimSpace(js), "script")+"\n"))
+	}
+
+	var out strings.Builder
+	if err := html.Render(&out, doc); err != nil {
+		return "", fmt.Errorf("failed to render html: %w", err)
+	}
+	return out.String(), nil
+}
+
+// escapeEndTag escapes the slash of every case-insensitive "</" + tag in
+// text.
+func escapeEndTag(text, tag string) string {
+	end := "</" + tag
+	var b strings.Builder
+	for {
+		i := indexFold(text, end)
+		if i < 0 {
+			b.WriteString(text)
+			return b.String()
+		}
+		b.WriteString(text[:i+1])
+		b.WriteByte('\\')
+		text = text[i+1:]
+	}
+}
+
+// indexFold is strings.Index matching ASCII case-insensitively.
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+func rawTextElement(tag atom.Atom, text string) *html.Node {
+	n := &html.Node{Type: html.ElementNode, DataAtom: tag, Data: tag.String()}
+	n.AppendChild(&html.Node{Type: html.TextNode, Data: text})
+	return n
+}
+
+func findElement(n *html.Node, tag atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == tag {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package parser
+
+import "testing"
+
+func TestHasCompleteBody(t *testing.T) {
+	tests := []struct {
+		doc  string
+		want bool
+	}{
+		{"", false},
+		{"<!DOCTYPE html><html><head><style>", false},
+		{"<html><body><h1>Hi</h1>", false},
+		{"<html><body><h1>Hi</h1></bo", false},
+		{"<html><body><h1>Hi</h1></body>", true},
+		{"<html><BODY class=\"x\"><p></p></BODY></html>", true},
+		{"<script>var s = '<body></body>';</script>", false},
+	}
+
+	for _, tt := range tests {
+		if got := HasCompleteBody(tt.doc); got != tt.want {
+			t.Errorf("HasCompleteBody(%q) = %v, want %v", tt.doc, got, tt.want)
+		}
+	}
+}
+
+func TestInlineAssets(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		css  string
+		js   string
+		want string
+	}{
+		{
+			name: "full document",
+			html: "<!DOCTYPE html><html><head><title>T</title></head><body><h1>Hi</h1></body></html>",
+			css:  "h1 { color: red; }",
+			js:   "console.log('hi');",
+			want: "<!DOCTYPE html><html><head><title>T</title><style>\nh1 { color: red; }\n</style></head><body><h1>Hi</h1><script>\nconsole.log('hi');\n</script></body></html>",
+		},
+		{
+			name: "fragment without head or body",
+			html: "<h1>Hi</h1>",
+			css:  "h1 {}",
+			js:   "x()",
+			want: "<!DOCTYPE html><html><head><style>\nh1 {}\n</style></head><body><h1>Hi</h1><script>\nx()\n</script></body></html>",
+		},
+		{
+			name: "empty html",
+			css:  "body {}",
+			want: "<!DOCTYPE html><html><head><style>\nbody {}\n</style></head><body></body></html>",
+		},
+		{
+			name: "js with markup characters is not escaped",
+			html: "<body></body>",
+			js:   "if (a < b && c > d) { el.innerHTML = '<p>x</p>'; }",
+			want: "<!DOCTYPE html><html><head></head><body><script>\nif (a < b && c > d) { el.innerHTML = '<p>x</p>'; }\n</script></body></html>",
+		},
+		{
+			name: "script end tag in js is escaped",
+			html: "<body></body>",
+			js:   `document.write("<script>x()</script>"); // </SCRIPT>`,
+			want: "<!DOCTYPE html><html><head></head><body><script>\n" + `document.write("<script>x()<\/script>"); // <\/SCRIPT>` + "\n</script></body></html>",
+		},
+		{
+			name: "style end tag in css is escaped",
+			html: "<body></body>",
+			css:  `p::after { content: "</style>"; }`,
+			want: "<!DOCTYPE html><html><head><style>\n" + `p::after { content: "<\/style>"; }` + "\n</style></head><body></body></html>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := InlineAssets(tt.html, tt.css, tt.js)
+			if err != nil {
+				t.Fatalf("InlineAssets failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("InlineAssets() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var ErrNoNamedBlocks = errors.New("no code block with a filename found")
+
+// filenamePattern matches relative paths such as index.html or src/app.js.
+// An extension is required so language tags like "html" don't qualify.
+var filenamePattern = regexp.MustCompile(`^[\w.\-]+(/[\w.\-]+)*\.\w+$`)
+
+// fileCommentPattern matches a "file:" label in a line, line or block
+// comment, e.g. "// file: app.js", "# filename: main.py" or
+// "<!-- file: index.html -->".
+var fileCommentPattern = regexp.MustCompile(`^\s*(?://|#|<!--|/\*)\s*(?i:file(?:name)?|path)\s*:\s*(\S+?)\s*(?:-->|\*/)?\s*$`)
+
+// ParseMultiFileResponse splits a reply made of several fenced code blocks
+// into files keyed by name. A block's name comes from, in order: a filename
+// in the fence's info string ("```html index.html", "```css title=style.css"),
+// a "file:" comment on its first line, which is dropped, or the last
+// non-blank line before the fence, such as "### index.html",
+// "**style.css**" or "`script.js`:". Names are cleaned by
+// SanitizeFilename, and one that is unsafe, such as "../../etc/x", fails
+// with ErrUnsafeFilename. Blocks without a name are skipped;
+// ErrNoNamedBlocks is returned if none has one, and a name used twice is
+// an error.
+func ParseMultiFileResponse(raw string) (map[string]string, error) {
+	files := make(map[string]string)
+	var label string
+
+	for pos := 0; pos < len(raw); {
+		line, next := nextLine(raw, pos)
+		pos = next
+
+		open, ok := parseOpeningFence(line)
+		if !ok {
+			if strings.TrimSpace(line) != "" {
+				label = line
+			}
+			continue
+		}
+
+		body, after := readBlock(raw, next, open)
+		pos = after
+
+		name := infoFilename(open.info)
+		if name == "" {
+			first, rest, _ := strings.Cut(body, "\n")
+			if m := fileCommentPattern.FindStringSubmatch(strings.TrimRight(first, "\r")); m != nil && filenamePattern.MatchString(m[1]) {
+				name, body = m[1], rest
+			}
+		}
+		if name == "" {
+			name = headingFilename(label)
+		}
+		label = ""
+
+		if name == "" {
+			continue
+		}
+		name, err := SanitizeFilename(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := files[name]; dup {
+			return nil, fmt.Errorf("duplicate file %q in response", name)
+		}
+		files[name] = strings.TrimSpace(body)
+	}
+
+	if len(files) == 0 {
+		return nil, ErrNoNamedBlocks
+	}
+	return files, nil
+}
+
+// infoFilename returns the filename given in a fence info string, either as
+// a bare word, a title= or file= attribute, or after a "lang:" prefix.
+func infoFilename(info string) string {
+	for _, field := range strings.Fields(info) {
+		if _, v, ok := strings.Cut(field, "="); ok {
+			field = v
+		} else if _, v, ok := strings.Cut(field, ":"); ok {
+			field = v
+		}
+		field = strings.Trim(field, `"'`)
+		if filenamePattern.MatchString(field) {
+			return field
+		}
+	}
+	return ""
+}
+
+// headingFilename returns the filename labeled by a line preceding a fence,
+// after stripping markdown decoration and an optional "File:" prefix.
+func headingFilename(line string) string {
+	s := strings.TrimSpace(line)
+	s = strings.TrimLeft(s, "#>-* \t")
+	s = strings.TrimSpace(s)
+	if k, v, ok := strings.Cut(s, ":"); ok && strings.TrimSpace(v) != "" {
+		switch strings.ToLower(strings.Trim(k, "*_` ")) {
+		case "file", "filename", "path":
+			s = v
+		}
+	}
+	s = strings.Trim(s, "*_`: \t")
+	if filenamePattern.MatchString(s) {
+		return s
+	}
+	return ""
+}
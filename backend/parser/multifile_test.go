@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseMultiFileResponse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]string
+	}{
+		{
+			name:  "info string",
+			input: "```html index.html\n<p>hi</p>\n```\n\n```css title=\"style.css\"\nbody{}\n```",
+			want:  map[string]string{"index.html": "<p>hi</p>", "style.css": "body{}"},
+		},
+		{
+			name:  "lang prefix",
+			input: "```js:src/app.js\nrun()\n```",
+			want:  map[string]string{"src/app.js": "run()"},
+		},
+		{
+			name:  "file comments",
+			input: "```js\n// file: script.js\nrun()\n```\n```html\n<!-- file: index.html -->\n<p></p>\n```\n```python\n# filename: main.py\nprint()\n```",
+			want:  map[string]string{"script.js": "run()", "index.html": "<p></p>", "main.py": "print()"},
+		},
+		{
+			name:  "headings",
+			input: "Here you go.\n\n### index.html\n```html\n<p></p>\n```\n\n**style.css**\n\n```css\nbody{}\n```\n\nFile: `script.js`\n```js\nrun()\n```",
+			want:  map[string]string{"index.html": "<p></p>", "style.css": "body{}", "script.js": "run()"},
+		},
+		{
+			name:  "unnamed block skipped",
+			input: "Install with:\n```sh\nnpm i\n```\n`index.html`:\n```html\n<p></p>\n```",
+			want:  map[string]string{"index.html": "<p></p>"},
+		},
+		{
+			name:  "heading does not carry over",
+			input: "## index.html\n```html\n<p></p>\n```\n```\nnotes\n```",
+			want:  map[string]string{"index.html": "<p></p>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMultiFileResponse(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMultiFileResponseErrors(t *testing.T) {
+	if _, err := ParseMultiFileResponse("```html\n<p></p>\n```"); !errors.Is(err, ErrNoNamedBlocks) {
+		t.Errorf("expected ErrNoNamedBlocks, got %v", err)
+	}
+	if _, err := ParseMultiFileResponse("```css a.css\n```\n```css a.css\n```"); err == nil {
+		t.Error("expected error for duplicate file")
+	}
+	if _, err := ParseMultiFileResponse("```css a.css\n```\n```css ./a.css\n```"); err == nil {
+		t.Error("expected error for a duplicate after cleaning")
+	}
+	for _, raw := range []string{
+		"```js ../../etc/x.js\nalert(1)\n```",
+		"### ../secret.txt\n```\nx\n```",
+		"```js\n// file: a/../../b.js\nalert(1)\n```",
+	} {
+		if _, err := ParseMultiFileResponse(raw); !errors.Is(err, ErrUnsafeFilename) {
+			t.Errorf("ParseMultiFileResponse(%q): expected ErrUnsafeFilename, got %v", raw, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package parser
+
+import "strings"
+
+// NormalizeHTML cleans up common model artifacts around an extracted HTML
+// document. It trims surrounding whitespace, drops stray ``` fence lines
+// left at the end, drops plain text before the <!DOCTYPE or <html, and
+// undoes doubled newlines when every line of the document is followed by
+// a blank one. Anything else, including documents with neither tag, is
+// left alone.
+func NormalizeHTML(html string) string {
+	doc := strings.TrimSpace(html)
+
+	for {
+		trimmed := strings.TrimRight(doc, " \t\r\n")
+		last := trimmed[strings.LastIndexByte(trimmed, '\n')+1:]
+		if strings.Trim(last, "`~ \t") != "" || len(strings.Trim(last, " \t")) < 3 {
+			break
+		}
+		doc = strings.TrimRight(trimmed[:len(trimmed)-len(last)], " \t\r\n")
+	}
+
+	if start := documentStart(doc); start > 0 && !strings.Contains(doc[:start], "<") {
+		doc = doc[start:]
+	}
+
+	return undoubleNewlines(doc)
+}
+
+// documentStart returns the offset of the first <!DOCTYPE or <html, or -1.
+// The tags are matched case-insensitively in place, since lowercasing the
+// whole document can change the length of non-ASCII text before them.
+func documentStart(doc string) int {
+	for i := 0; i < len(doc); i++ {
+		if doc[i] != '<' {
+			continue
+		}
+		for _, tag := range []string{"<!doctype", "<html"} {
+			if len(doc)-i >= len(tag) && strings.EqualFold(doc[i:i+len(tag)], tag) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func undoubleNewlines(doc string) string {
+	lines := strings.Split(doc, "\n")
+	if len(lines) < 5 {
+		return doc
+	}
+
+	for i, line := range lines {
+		blank := strings.TrimSpace(line) == ""
+		if blank != (i%2 == 1) {
+			return doc
+		}
+	}
+
+	kept := make([]string, 0, len(lines)/2+1)
+	for i := 0; i < len(lines); i += 2 {
+		kept = append(kept, lines[i])
+	}
+	return strings.Join(kept, "\n")
+}
@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "already clean",
+			in:   "<!DOCTYPE html>\n<html>\n\n<body></body>\n</html>",
+			want: "<!DOCTYPE html>\n<html>\n\n<body></body>\n</html>",
+		},
+		{
+			name: "surrounding whitespace",
+			in:   "\n\n  <html></html>  \n",
+			want: "<html></html>",
+		},
+		{
+			name: "stray fence",
+			in:   "<html></html>\n```\n```",
+			want: "<html></html>",
+		},
+		{
+			name: "leading text",
+			in:   "html\n<!DOCTYPE html><html></html>",
+			want: "<!DOCTYPE html><html></html>",
+		},
+		{
+			name: "doubled newlines",
+			in:   "<!DOCTYPE html>\n\n<html>\n\n<body>\n\n</body>\n\n</html>",
+			want: "<!DOCTYPE html>\n<html>\n<body>\n</body>\n</html>",
+		},
+		{
+			name: "markup before html kept",
+			in:   "<!-- generated -->\n<html></html>",
+			want: "<!-- generated -->\n<html></html>",
+		},
+		{
+			name: "fragment left alone",
+			in:   "<div>hi</div>",
+			want: "<div>hi</div>",
+		},
+		{
+			name: "backticks in content kept",
+			in:   "<html><body><code>```</code></body></html>",
+			want: "<html><body><code>```</code></body></html>",
+		},
+		{
+			name: "non-ascii text before html",
+			in:   "İİ intro <html><body>x</body></html>",
+			want: "<html><body>x</body></html>",
+		},
+		{
+			name: "non-ascii text that lowercases longer",
+			in:   strings.Repeat("Ⱥ", 20) + "<HTML>",
+			want: "<HTML>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeHTML(tt.in); got != tt.want {
+				t.Errorf("NormalizeHTML(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
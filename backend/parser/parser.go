@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrNoCodeBlock = errors.New("no code block found")
+
+// ExtractCodeBlock returns the contents and language tag of the first
+// fenced markdown code block in text. Fences may be indented, use three or
+// more backticks or tildes, or carry no language tag (lang is then empty).
+// An unclosed fence runs to the end of text, which covers truncated output.
+func ExtractCodeBlock(text string) (code string, lang string, err error) {
+	for pos := 0; pos < len(text); {
+		line, next := nextLine(text, pos)
+		pos = next
+
+		open, ok := parseOpeningFence(line)
+		if !ok {
+			continue
+		}
+
+		body, _ := readBlock(text, next, open)
+		return strings.TrimSpace(body), open.lang, nil
+	}
+
+	return "", "", ErrNoCodeBlock
+}
+
+// readBlock returns the body of the block opened by open whose first line
+// starts at pos, and the position after its closing fence.
+func readBlock(text string, pos int, open fence) (string, int) {
+	end, after := len(text), len(text)
+	for p := pos; p < len(text); {
+		l, n := nextLine(text, p)
+		if open.closedBy(l) {
+			end, after = p, n
+			break
+		}
+		p = n
+	}
+
+	// Slice the input directly unless lines need rewriting, which keeps
+	// large responses from being copied.
+	body := text[pos:end]
+	if open.indent != "" || strings.Contains(body, "\r") {
+		body = dedent(body, open.indent)
+	}
+	return body, after
+}
+
+// nextLine returns the line starting at pos, without its newline, and the
+// position of the following line.
+func nextLine(text string, pos int) (string, int) {
+	i := strings.IndexByte(text[pos:], '\n')
+	if i < 0 {
+		return text[pos:], len(text)
+	}
+	return text[pos : pos+i], pos + i + 1
+}
+
+func dedent(body, indent string) string {
+	lines := strings.Split(body, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimPrefix(strings.TrimRight(l, "\r"), indent)
+	}
+	return strings.Join(lines, "\n")
+}
+
+type fence struct {
+	indent string
+	char   byte
+	size   int
+	lang   string
+	info   string
+}
+
+func parseOpeningFence(line string) (fence, bool) {
+	line = strings.TrimRight(line, "\r")
+	trimmed := strings.TrimLeft(line, " \t")
+
+	char, size := fenceRun(trimmed)
+	if size < 3 {
+		return fence{}, false
+	}
+
+	info := strings.TrimSpace(trimmed[size:])
+	if char == '`' && strings.Contains(info, "`") {
+		// Inline code such as ```foo``` rather than a fence.
+		return fence{}, false
+	}
+
+	var lang string
+	if fields := strings.Fields(info); len(fields) > 0 {
+		lang = fields[0]
+	}
+
+	return fence{
+		indent: line[:len(line)-len(trimmed)],
+		char:   char,
+		size:   size,
+		lang:   lang,
+		info:   info,
+	}, true
+}
+
+func (f fence) closedBy(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	char, size := fenceRun(trimmed)
+	return char == f.char && size >= f.size && size == len(trimmed)
+}
+
+// fenceRun reports the fence character starting s and how many times it
+// repeats.
+func fenceRun(s string) (byte, int) {
+	if s == "" || (s[0] != '`' && s[0] != '~') {
+		return 0, 0
+	}
+
+	n := 0
+	for n < len(s) && s[n] == s[0] {
+		n++
+	}
+	return s[0], n
+}
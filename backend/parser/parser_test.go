@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExtractCodeBlock(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantCode string
+		wantLang string
+	}{
+		{
+			name:     "html tag",
+			input:    "```html\n<html></html>\n```",
+			wantCode: "<html></html>",
+			wantLang: "html",
+		},
+		{
+			name:     "surrounding prose",
+			input:    "Here is your page:\n\n```html\n<p>hi</p>\n```\n\nEnjoy!",
+			wantCode: "<p>hi</p>",
+			wantLang: "html",
+		},
+		{
+			name:     "no language tag",
+			input:    "```\n<html></html>\n```",
+			wantCode: "<html></html>",
+			wantLang: "",
+		},
+		{
+			name:     "indented fence",
+			input:    "1. Save this:\n   ```html\n   <html>\n     <body></body>\n   </html>\n   ```",
+			wantCode: "<html>\n  <body></body>\n</html>",
+			wantLang: "html",
+		},
+		{
+			name:     "four backticks containing three",
+			input:    "````markdown\n```js\nx()\n```\n````",
+			wantCode: "```js\nx()\n```",
+			wantLang: "markdown",
+		},
+		{
+			name:     "tilde fence",
+			input:    "~~~html\n<p></p>\n~~~",
+			wantCode: "<p></p>",
+			wantLang: "html",
+		},
+		{
+			name:     "info string with attributes",
+			input:    "```html title=\"index.html\"\n<p></p>\n```",
+			wantCode: "<p></p>",
+			wantLang: "html",
+		},
+		{
+			name:     "crlf line endings",
+			input:    "```html\r\n<p></p>\r\n```\r\n",
+			wantCode: "<p></p>",
+			wantLang: "html",
+		},
+		{
+			name:     "unclosed fence",
+			input:    "```html\n<html><body>",
+			wantCode: "<html><body>",
+			wantLang: "html",
+		},
+		{
+			name:     "first of several blocks",
+			input:    "```css\nbody{}\n```\n```js\nx()\n```",
+			wantCode: "body{}",
+			wantLang: "css",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, lang, err := ExtractCodeBlock(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if code != tt.wantCode {
+				t.Errorf("code = %q, want %q", code, tt.wantCode)
+			}
+			if lang != tt.wantLang {
+				t.Errorf("lang = %q, want %q", lang, tt.wantLang)
+			}
+		})
+	}
+}
+
+func TestExtractCodeBlockNoBlock(t *testing.T) {
+	inputs := []string{
+		"",
+		"just some prose",
+		"inline ```code``` only",
+		"``two backticks``",
+	}
+
+	for _, input := range inputs {
+		if _, _, err := ExtractCodeBlock(input); !errors.Is(err, ErrNoCodeBlock) {
+			t.Errorf("ExtractCodeBlock(%q): expected ErrNoCodeBlock, got %v", input, err)
+		}
+	}
+}
+
+// largeResponse mimics a ~100KB model reply: prose, then one big fenced
+// HTML document.
+func largeResponse() string {
+	var b strings.Builder
+	b.WriteString("Here is your application:\n\n```html\n<!DOCTYPE html>\n<html>\n<head>\n<style>\n")
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&b, "    .item-%d { color: #%06x; padding: %dpx; }\n", i, i*997, i%20)
+	}
+	b.WriteString("</style>\n</head>\n<body>\n")
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&b, "    <div class=\"item-%d\">Item %d</div>\n", i%500, i)
+	}
+	b.WriteString("</body>\n</html>\n```\n\nLet me know if you want changes.")
+	return b.String()
+}
+
+func BenchmarkExtractCodeBlock(b *testing.B) {
+	input := largeResponse()
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if _, _, err := ExtractCodeBlock(input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
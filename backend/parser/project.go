@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrUnsafeFilename  = errors.New("unsafe filename")
+	ErrProjectTooLarge = errors.New("project exceeds size limits")
+)
+
+const (
+	defaultMaxProjectFiles = 100
+	defaultMaxProjectBytes = 5 << 20
+)
+
+// ProjectLimits caps the files a generated project may contain. Zero
+// fields use the defaults of 100 files and 5 MiB in total.
+type ProjectLimits struct {
+	MaxFiles int
+	MaxBytes int
+}
+
+// SanitizeFilename checks a model-supplied path for a generated file and
+// returns it cleaned, with forward slashes as separators. Absolute paths,
+// drive letters, ".." segments and null bytes are rejected with
+// ErrUnsafeFilename.
+func SanitizeFilename(name string) (string, error) {
+	if strings.ContainsRune(name, 0) {
+		return "", fmt.Errorf("%w: %q contains a null byte", ErrUnsafeFilename, name)
+	}
+
+	slashed := strings.ReplaceAll(name, `\`, "/")
+	if strings.HasPrefix(slashed, "/") || hasDriveLetter(slashed) {
+		return "", fmt.Errorf("%w: %q is absolute", ErrUnsafeFilename, name)
+	}
+	for _, segment := range strings.Split(slashed, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("%w: %q escapes the project", ErrUnsafeFilename, name)
+		}
+	}
+
+	cleaned := path.Clean(slashed)
+	if cleaned == "." {
+		return "", fmt.Errorf("%w: %q is empty", ErrUnsafeFilename, name)
+	}
+	return cleaned, nil
+}
+
+func hasDriveLetter(name string) bool {
+	return len(name) >= 2 && name[1] == ':' &&
+		('a' <= name[0] && name[0] <= 'z' || 'A' <= name[0] && name[0] <= 'Z')
+}
+
+// SanitizeFiles applies SanitizeFilename to every path in files, a map of
+// path to contents, and enforces limits, failing with ErrProjectTooLarge
+// when the project has too many files or bytes. Paths that clean to the
+// same name are rejected as well.
+func SanitizeFiles(files map[string]string, limits ProjectLimits) (map[string]string, error) {
+	maxFiles := limits.MaxFiles
+	if maxFiles <= 0 {
+		maxFiles = defaultMaxProjectFiles
+	}
+	maxBytes := limits.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxProjectBytes
+	}
+
+	if len(files) > maxFiles {
+		return nil, fmt.Errorf("%w: %d files, limit %d", ErrProjectTooLarge, len(files), maxFiles)
+	}
+
+	// Sorted so the error for a bad manifest doesn't depend on map order.
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := make(map[string]string, len(files))
+	total := 0
+	for _, name := range names {
+		cleaned, err := SanitizeFilename(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := out[cleaned]; ok {
+			return nil, fmt.Errorf("%w: %q duplicates another file", ErrUnsafeFilename, name)
+		}
+
+		total += len(files[name])
+		if total > maxBytes {
+			return nil, fmt.Errorf("%w: more than %d bytes", ErrProjectTooLarge, maxBytes)
+		}
+		out[cleaned] = files[name]
+	}
+	return out, nil
+}
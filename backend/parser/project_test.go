@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "index.html", want: "index.html"},
+		{name: `css\style.css`, want: "css/style.css"},
+		{name: "./js//app.js", want: "js/app.js"},
+		{name: "/etc/passwd", wantErr: true},
+		{name: `C:\Windows\win.ini`, wantErr: true},
+		{name: "../secret", wantErr: true},
+		{name: `assets\..\..\x`, wantErr: true},
+		{name: "index.html\x00.png", wantErr: true},
+		{name: "", wantErr: true},
+		{name: "./", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := SanitizeFilename(tt.name)
+		if tt.wantErr {
+			if !errors.Is(err, ErrUnsafeFilename) {
+				t.Errorf("SanitizeFilename(%q): expected ErrUnsafeFilename, got %q, %v", tt.name, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("SanitizeFilename(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestSanitizeFiles(t *testing.T) {
+	files, err := SanitizeFiles(map[string]string{
+		"index.html":       "<html></html>",
+		`css\style.css`:    "body{}",
+		"./js/../js/a.js":  "",
+		"assets/logo.svg ": "<svg/>",
+	}, ProjectLimits{})
+	if !errors.Is(err, ErrUnsafeFilename) {
+		t.Errorf("expected the .. path to be rejected, got %v, %v", files, err)
+	}
+
+	files, err = SanitizeFiles(map[string]string{
+		"index.html":    "<html></html>",
+		`css\style.css`: "body{}",
+	}, ProjectLimits{})
+	if err != nil {
+		t.Fatalf("SanitizeFiles failed: %v", err)
+	}
+	if len(files) != 2 || files["css/style.css"] != "body{}" {
+		t.Errorf("unexpected files %v", files)
+	}
+
+	if _, err := SanitizeFiles(map[string]string{"a.js": "", "./a.js": ""}, ProjectLimits{}); !errors.Is(err, ErrUnsafeFilename) {
+		t.Errorf("expected duplicate paths to be rejected, got %v", err)
+	}
+}
+
+func TestSanitizeFiles_Limits(t *testing.T) {
+	files := map[string]string{"a.html": "12345", "b.css": "12345", "c.js": "12345"}
+
+	if _, err := SanitizeFiles(files, ProjectLimits{MaxFiles: 2}); !errors.Is(err, ErrProjectTooLarge) {
+		t.Errorf("expected the file limit to apply, got %v", err)
+	}
+	if _, err := SanitizeFiles(files, ProjectLimits{MaxBytes: 14}); !errors.Is(err, ErrProjectTooLarge) {
+		t.Errorf("expected the byte limit to apply, got %v", err)
+	}
+	if _, err := SanitizeFiles(files, ProjectLimits{MaxFiles: 3, MaxBytes: 15}); err != nil {
+		t.Errorf("expected files at the limits to pass, got %v", err)
+	}
+
+	big := map[string]string{"a.html": strings.Repeat("x", defaultMaxProjectBytes+1)}
+	if _, err := SanitizeFiles(big, ProjectLimits{}); !errors.Is(err, ErrProjectTooLarge) {
+		t.Errorf("expected the default byte limit to apply, got %v", err)
+	}
+}
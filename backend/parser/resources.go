@@ -0,0 +1,165 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// resourceAttrs maps tags to the attribute through which they load a
+// resource. <a href> is navigation, not loading, so it isn't listed.
+var resourceAttrs = map[string]string{
+	"script": "src",
+	"img":    "src",
+	"iframe": "src",
+	"embed":  "src",
+	"audio":  "src",
+	"video":  "src",
+	"source": "src",
+	"track":  "src",
+	"input":  "src",
+	"link":   "href",
+	"object": "data",
+}
+
+// closedElements have their end tag removed along with them when stripped.
+var closedElements = map[string]bool{
+	"script": true,
+	"iframe": true,
+	"audio":  true,
+	"video":  true,
+	"object": true,
+}
+
+var importRule = regexp.MustCompile(`@import\s+(?:url\(\s*)?['"]?([^'")\s;]+)['"]?\s*\)?[^;]*;?`)
+
+type resourceRef struct {
+	url        string
+	start, end int
+}
+
+// ExternalResources returns the absolute http(s) or protocol-relative URLs
+// doc loads: resource attributes such as <script src> and <link href>, and
+// @import rules in <style>. Code fences or text around the document are
+// ignored.
+func ExternalResources(doc string) []string {
+	refs := findExternalResources(doc)
+	urls := make([]string, len(refs))
+	for i, r := range refs {
+		urls[i] = r.url
+	}
+	return urls
+}
+
+// StripExternalResources removes the elements and @import rules that load
+// external resources from doc, leaving everything else byte for byte. It
+// returns the cleaned document and the removed URLs.
+func StripExternalResources(doc string) (string, []string) {
+	refs := findExternalResources(doc)
+	if len(refs) == 0 {
+		return doc, nil
+	}
+
+	var out strings.Builder
+	urls := make([]string, len(refs))
+	last := 0
+	for i, r := range refs {
+		out.WriteString(doc[last:r.start])
+		last = r.end
+		urls[i] = r.url
+	}
+	out.WriteString(doc[last:])
+	return out.String(), urls
+}
+
+func findExternalResources(doc string) []resourceRef {
+	var refs []resourceRef
+	z := html.NewTokenizer(strings.NewReader(doc))
+	pos := 0
+	inStyle := false
+	// open is the external element waiting for its end tag, by tag name.
+	var open *resourceRef
+	var openTag string
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		start := pos
+		pos += len(z.Raw())
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := string(name)
+			if tag == "style" && tt == html.StartTagToken {
+				inStyle = true
+			}
+
+			attr, ok := resourceAttrs[tag]
+			if !ok || !hasAttr || open != nil {
+				continue
+			}
+			url := attrValue(z, attr)
+			if !isExternalURL(url) {
+				continue
+			}
+
+			ref := resourceRef{url: url, start: start, end: pos}
+			if tt == html.StartTagToken && closedElements[tag] {
+				open, openTag = &ref, tag
+				continue
+			}
+			refs = append(refs, ref)
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			if string(name) == "style" {
+				inStyle = false
+			}
+			if open != nil && string(name) == openTag {
+				open.end = pos
+				refs = append(refs, *open)
+				open = nil
+			}
+
+		case html.TextToken:
+			if !inStyle || open != nil {
+				continue
+			}
+			text := string(z.Raw())
+			for _, m := range importRule.FindAllStringSubmatchIndex(text, -1) {
+				url := text[m[2]:m[3]]
+				if isExternalURL(url) {
+					refs = append(refs, resourceRef{url: url, start: start + m[0], end: start + m[1]})
+				}
+			}
+		}
+	}
+
+	// An element left unclosed is removed through the end of the document.
+	if open != nil {
+		open.end = len(doc)
+		refs = append(refs, *open)
+	}
+	return refs
+}
+
+func attrValue(z *html.Tokenizer, name string) string {
+	for {
+		key, val, more := z.TagAttr()
+		if string(key) == name {
+			return strings.TrimSpace(string(val))
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+func isExternalURL(url string) bool {
+	lower := strings.ToLower(url)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "//")
+}
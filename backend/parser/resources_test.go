@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+const externalDoc = `<!DOCTYPE html>
+<html>
+<head>
+<link rel="stylesheet" href="https://cdn.example.com/bulma.css">
+<link rel="icon" href="favicon.ico">
+<style>
+@import url("https://fonts.googleapis.com/css?family=Inter");
+body { margin: 0; }
+</style>
+<script src="//cdn.example.com/vue.js"></script>
+</head>
+<body>
+<a href="https://example.com">docs</a>
+<img src="https://placehold.co/600x400" alt="">
+<img src="data:image/png;base64,AAAA" alt="">
+<script>console.log("inline")</script>
+</body>
+</html>`
+
+func TestExternalResources(t *testing.T) {
+	got := ExternalResources(externalDoc)
+	want := []string{
+		"https://cdn.example.com/bulma.css",
+		"https://fonts.googleapis.com/css?family=Inter",
+		"//cdn.example.com/vue.js",
+		"https://placehold.co/600x400",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExternalResources() = %q, want %q", got, want)
+	}
+
+	if got := ExternalResources("```html\n<html><body><p>hi</p></body></html>\n```"); len(got) != 0 {
+		t.Errorf("expected no resources, got %q", got)
+	}
+}
+
+func TestStripExternalResources(t *testing.T) {
+	got, removed := StripExternalResources(externalDoc)
+	if len(removed) != 4 {
+		t.Errorf("removed %q", removed)
+	}
+	if left := ExternalResources(got); len(left) != 0 {
+		t.Errorf("resources left after strip: %q\n%s", left, got)
+	}
+
+	want := `<!DOCTYPE html>
+<html>
+<head>
+
+<link rel="icon" href="favicon.ico">
+<style>
+
+body { margin: 0; }
+</style>
+
+</head>
+<body>
+<a href="https://example.com">docs</a>
+
+<img src="data:image/png;base64,AAAA" alt="">
+<script>console.log("inline")</script>
+</body>
+</html>`
+	if got != want {
+		t.Errorf("StripExternalResources() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestStripExternalResources_Unchanged(t *testing.T) {
+	doc := "<!DOCTYPE html><html><body></body></html>"
+	got, removed := StripExternalResources(doc)
+	if got != doc || removed != nil {
+		t.Errorf("expected doc unchanged, got %q removed %q", got, removed)
+	}
+}